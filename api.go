@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the JSON API for our own clients, built from the joined Data:
+//
+//	GET /api/v1/artists                 ?page=&per_page=&sort=id|name|creationDate|firstAlbum|concerts
+//	GET /api/v1/artists/{id}
+//	GET /api/v1/artists/{id}/concerts   ?page=&per_page=&sort=date
+//	GET /api/v1/artists/{id}/tours      ?page=&per_page=&sort=number|shows|countries|distance
+//	GET /api/v1/locations               ?page=&per_page=&sort=name|concerts|artists
+//	GET /api/v1/concerts                ?page=&per_page=&sort=date|artist|location
+//	GET /api/v1/nearby                  ?q=|lat=&lon=, radius= (km); see nearby.go
+//
+// sort keys take a "-" prefix for descending order. lists come wrapped in
+// apiList. errors are returned as *AppError, which renderError writes as an
+// apiErrorBody for every /api/ path.
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// an artist as the API returns it
+type apiArtist struct {
+	Id           uint     `json:"id"`
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	Image        string   `json:"image"`
+	Members      []string `json:"members"`
+	CreationDate uint     `json:"creationDate"`
+	FirstAlbum   string   `json:"firstAlbum"` // as the upstream API writes it, DD-MM-YYYY
+	Concerts     int      `json:"concerts"`   // number of concerts
+	Tours        int      `json:"tours"`      // number of tours
+	URL          string   `json:"url"`        // the artist's HTML page
+}
+
+// a concert as the API returns it
+type apiConcert struct {
+	ArtistID   uint     `json:"artistId"`
+	Artist     string   `json:"artist"`
+	Location   string   `json:"location"` // slug, e.g. "north_carolina-usa"
+	Place      string   `json:"place"`    // "North Carolina, USA"
+	City       string   `json:"city"`
+	Region     string   `json:"region,omitempty"`
+	Country    string   `json:"country"`
+	Lat        *float64 `json:"lat,omitempty"` // unset when the location couldn't be geocoded
+	Lon        *float64 `json:"lon,omitempty"`
+	Date       string   `json:"date"` // YYYY-MM-DD
+	TourOpener bool     `json:"tourOpener"`
+	Upcoming   bool     `json:"upcoming"` // today or later
+}
+
+// a tour of an artist with its statistics
+type apiTour struct {
+	Number     int          `json:"number"`
+	FirstDate  string       `json:"firstDate"` // YYYY-MM-DD
+	LastDate   string       `json:"lastDate"`
+	Shows      int          `json:"shows"`
+	Countries  []string     `json:"countries"`
+	DistanceKm float64      `json:"distanceKm"`
+	Unlocated  int          `json:"unlocated"` // shows left out of distanceKm
+	Concerts   []apiConcert `json:"concerts"`
+}
+
+// a concert location with how often it was played
+type apiLocation struct {
+	Location string   `json:"location"`
+	Place    string   `json:"place"`
+	City     string   `json:"city"`
+	Region   string   `json:"region,omitempty"`
+	Country  string   `json:"country"`
+	Lat      *float64 `json:"lat,omitempty"` // unset when the location couldn't be geocoded
+	Lon      *float64 `json:"lon,omitempty"`
+	Concerts int      `json:"concerts"`
+	Artists  int      `json:"artists"` // number of different artists
+}
+
+// the answer of /api/v1/nearby: concerts within RadiusKm of Origin, grouped by artist
+type apiNearby struct {
+	Origin   apiOrigin         `json:"origin"`
+	RadiusKm float64           `json:"radiusKm"`
+	Upcoming []apiNearbyArtist `json:"upcoming"`
+	Past     []apiNearbyArtist `json:"past"`
+}
+
+type apiOrigin struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+type apiNearbyArtist struct {
+	ArtistID  uint               `json:"artistId"`
+	Artist    string             `json:"artist"`
+	URL       string             `json:"url"`
+	NearestKm float64            `json:"nearestKm"`
+	Concerts  []apiNearbyConcert `json:"concerts"` // closest first
+}
+
+type apiNearbyConcert struct {
+	apiConcert
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// one page of a list
+type apiList struct {
+	Data       interface{} `json:"data"`
+	Page       int         `json:"page"`
+	PerPage    int         `json:"perPage"`
+	Total      int         `json:"total"`
+	TotalPages int         `json:"totalPages"`
+}
+
+// the body of every error response: {"error": {"status": 404, "message": "..."}}
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func newAPIArtist(d Data) apiArtist {
+	return apiArtist{
+		Id:           d.A.Id,
+		Slug:         d.Slug,
+		Name:         d.A.Name,
+		Image:        d.A.Image,
+		Members:      d.A.Members,
+		CreationDate: d.A.CreationDate,
+		FirstAlbum:   d.A.FirstAlbum,
+		Concerts:     len(d.Concerts),
+		Tours:        len(d.Tours),
+		URL:          d.URL(),
+	}
+}
+
+func newAPIConcert(d Data, c Concert, now time.Time) apiConcert {
+	lat, lon := apiCoord(c.Place)
+	return apiConcert{
+		ArtistID:   c.ArtistID,
+		Artist:     d.A.Name,
+		Location:   c.Slug,
+		Place:      c.Place.String(),
+		City:       c.City,
+		Region:     c.Region,
+		Country:    c.Country,
+		Lat:        lat,
+		Lon:        lon,
+		Date:       c.Date.Format("2006-01-02"),
+		TourOpener: c.TourOpener,
+		Upcoming:   c.Upcoming(now),
+	}
+}
+
+// the coordinates of p, or nils when it isn't located
+func apiCoord(p Place) (lat, lon *float64) {
+	if !p.Located {
+		return nil, nil
+	}
+	return &p.Lat, &p.Lon
+}
+
+// routes every /api/v1/ request
+func apiHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return methodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+	}
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	now := clock.Now() // one instant for the whole response
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "artists":
+		return apiArtists(w, r, snap)
+	case len(parts) == 1 && parts[0] == "locations":
+		return apiLocations(w, r, snap)
+	case len(parts) == 1 && parts[0] == "concerts":
+		return apiConcerts(w, r, snap, now)
+	case len(parts) == 1 && parts[0] == "nearby":
+		return apiNearbyConcerts(w, r, snap, now)
+	case len(parts) >= 2 && len(parts) <= 3 && parts[0] == "artists":
+		if !isID(parts[1]) {
+			return newAppError(http.StatusBadRequest, fmt.Sprintf("artist id %q is not a number", parts[1]), nil)
+		}
+		// an id too big for a uint can't be an artist either
+		id, err := strconv.ParseUint(parts[1], 10, 0)
+		d, ok := snap.Artist(uint(id))
+		if err != nil || !ok {
+			return newAppError(http.StatusNotFound, "no artist with id "+parts[1], nil)
+		}
+		if len(parts) == 2 {
+			return writeJSON(w, http.StatusOK, newAPIArtist(d))
+		}
+		switch parts[2] {
+		case "concerts":
+			return apiArtistConcerts(w, r, d, now)
+		case "tours":
+			return apiArtistTours(w, r, d, now)
+		}
+	}
+	return newAppError(http.StatusNotFound, "no such endpoint: "+r.URL.Path, nil)
+}
+
+// GET /api/v1/artists
+func apiArtists(w http.ResponseWriter, r *http.Request, snap *Snapshot) error {
+	artists := make([]apiArtist, len(snap.Data))
+	for i, d := range snap.Data {
+		artists[i] = newAPIArtist(d)
+	}
+	less := map[string]func(a, b apiArtist) bool{
+		"id":           func(a, b apiArtist) bool { return a.Id < b.Id },
+		"name":         func(a, b apiArtist) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+		"creationDate": func(a, b apiArtist) bool { return a.CreationDate < b.CreationDate },
+		"firstAlbum": func(a, b apiArtist) bool {
+			return Artist{FirstAlbum: a.FirstAlbum}.FirstAlbumYear() < Artist{FirstAlbum: b.FirstAlbum}.FirstAlbumYear()
+		},
+		"concerts": func(a, b apiArtist) bool { return a.Concerts < b.Concerts },
+	}
+	return writeList(w, r, artists, less)
+}
+
+// GET /api/v1/artists/{id}/concerts
+func apiArtistConcerts(w http.ResponseWriter, r *http.Request, d Data, now time.Time) error {
+	concerts := make([]apiConcert, len(d.Concerts))
+	for i, c := range d.Concerts {
+		concerts[i] = newAPIConcert(d, c, now)
+	}
+	return writeList(w, r, concerts, concertSorts)
+}
+
+// GET /api/v1/artists/{id}/tours
+func apiArtistTours(w http.ResponseWriter, r *http.Request, d Data, now time.Time) error {
+	tours := make([]apiTour, len(d.Tours))
+	for i, t := range d.Tours {
+		tours[i] = apiTour{
+			Number:     t.Number,
+			FirstDate:  t.First.Format("2006-01-02"),
+			LastDate:   t.Last.Format("2006-01-02"),
+			Shows:      t.Shows(),
+			Countries:  t.Countries,
+			DistanceKm: roundKm(t.DistanceKm),
+			Unlocated:  t.Unlocated,
+			Concerts:   make([]apiConcert, len(t.Concerts)),
+		}
+		for j, c := range t.Concerts {
+			tours[i].Concerts[j] = newAPIConcert(d, c, now)
+		}
+	}
+	less := map[string]func(a, b apiTour) bool{
+		"number":    func(a, b apiTour) bool { return a.Number < b.Number },
+		"shows":     func(a, b apiTour) bool { return a.Shows < b.Shows },
+		"countries": func(a, b apiTour) bool { return len(a.Countries) < len(b.Countries) },
+		"distance":  func(a, b apiTour) bool { return a.DistanceKm < b.DistanceKm },
+	}
+	return writeList(w, r, tours, less)
+}
+
+// GET /api/v1/concerts
+func apiConcerts(w http.ResponseWriter, r *http.Request, snap *Snapshot, now time.Time) error {
+	var concerts []apiConcert
+	for _, d := range snap.Data {
+		for _, c := range d.Concerts {
+			concerts = append(concerts, newAPIConcert(d, c, now))
+		}
+	}
+	sort.SliceStable(concerts, func(i, j int) bool { return concerts[i].Date < concerts[j].Date })
+	return writeList(w, r, concerts, concertSorts)
+}
+
+// sort keys of concert lists. dates are YYYY-MM-DD so they compare as strings.
+var concertSorts = map[string]func(a, b apiConcert) bool{
+	"date":     func(a, b apiConcert) bool { return a.Date < b.Date },
+	"artist":   func(a, b apiConcert) bool { return strings.ToLower(a.Artist) < strings.ToLower(b.Artist) },
+	"location": func(a, b apiConcert) bool { return a.Place < b.Place },
+}
+
+// GET /api/v1/locations
+func apiLocations(w http.ResponseWriter, r *http.Request, snap *Snapshot) error {
+	bySlug := make(map[string]*apiLocation)
+	artistsAt := make(map[string]map[uint]bool)
+	for _, d := range snap.Data {
+		for _, c := range d.Concerts {
+			loc, ok := bySlug[c.Slug]
+			if !ok {
+				loc = &apiLocation{Location: c.Slug, Place: c.Place.String(), City: c.City, Region: c.Region, Country: c.Country}
+				loc.Lat, loc.Lon = apiCoord(c.Place)
+				bySlug[c.Slug] = loc
+				artistsAt[c.Slug] = make(map[uint]bool)
+			}
+			loc.Concerts++
+			artistsAt[c.Slug][d.A.Id] = true
+		}
+	}
+	locations := make([]apiLocation, 0, len(bySlug))
+	for slug, loc := range bySlug {
+		loc.Artists = len(artistsAt[slug])
+		locations = append(locations, *loc)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Place < locations[j].Place })
+
+	less := map[string]func(a, b apiLocation) bool{
+		"name":     func(a, b apiLocation) bool { return a.Place < b.Place },
+		"concerts": func(a, b apiLocation) bool { return a.Concerts < b.Concerts },
+		"artists":  func(a, b apiLocation) bool { return a.Artists < b.Artists },
+	}
+	return writeList(w, r, locations, less)
+}
+
+// GET /api/v1/nearby
+func apiNearbyConcerts(w http.ResponseWriter, r *http.Request, snap *Snapshot, now time.Time) error {
+	q, err := parseNearby(gazetteer, r.URL.Query())
+	if err != nil {
+		return nearbyError(err)
+	}
+	if q.Empty() {
+		return newAppError(http.StatusBadRequest, "Invalid search: give q (a city) or lat and lon", nil)
+	}
+	upcoming, past := findNearby(snap, q.Origin.Coord, q.RadiusKm, now)
+	return writeJSON(w, http.StatusOK, apiNearby{
+		Origin:   apiOrigin{Name: q.Origin.String(), Lat: q.Origin.Lat, Lon: q.Origin.Lon},
+		RadiusKm: q.RadiusKm,
+		Upcoming: newAPINearbyArtists(upcoming, now),
+		Past:     newAPINearbyArtists(past, now),
+	})
+}
+
+func newAPINearbyArtists(artists []nearbyArtist, now time.Time) []apiNearbyArtist {
+	out := make([]apiNearbyArtist, len(artists))
+	for i, a := range artists {
+		out[i] = apiNearbyArtist{
+			ArtistID:  a.Artist.A.Id,
+			Artist:    a.Artist.A.Name,
+			URL:       a.Artist.URL(),
+			NearestKm: roundKm(a.NearestKm),
+			Concerts:  make([]apiNearbyConcert, len(a.Concerts)),
+		}
+		for j, c := range a.Concerts {
+			out[i].Concerts[j] = apiNearbyConcert{apiConcert: newAPIConcert(a.Artist, c.Concert, now), DistanceKm: roundKm(c.DistanceKm)}
+		}
+	}
+	return out
+}
+
+// rounds to 100 m, which is as precise as the gazetteer
+func roundKm(km float64) float64 {
+	return math.Round(km*10) / 10
+}
+
+// sorts items by the ?sort= key found in less, then writes the page asked
+// for by ?page= and ?per_page= wrapped in an apiList
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T, less map[string]func(a, b T) bool) error {
+	q := r.URL.Query()
+	if key := q.Get("sort"); key != "" {
+		desc := strings.HasPrefix(key, "-")
+		cmp, ok := less[strings.TrimPrefix(key, "-")]
+		if !ok {
+			return newAppError(http.StatusBadRequest, fmt.Sprintf("cannot sort by %q, use one of: %s", key, sortKeys(less)), nil)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if desc {
+				return cmp(items[j], items[i])
+			}
+			return cmp(items[i], items[j])
+		})
+	}
+
+	page, perPage, err := parsePage(q)
+	if err != nil {
+		return newAppError(http.StatusBadRequest, err.Error(), nil)
+	}
+	// a page past the last is empty. checking before multiplying keeps a
+	// huge ?page= from overflowing into a negative index.
+	totalPages := (len(items) + perPage - 1) / perPage
+	start, end := len(items), len(items)
+	if page <= totalPages {
+		start = (page - 1) * perPage
+		if end > start+perPage {
+			end = start + perPage
+		}
+	}
+	return writeJSON(w, http.StatusOK, apiList{
+		Data:       items[start:end],
+		Page:       page,
+		PerPage:    perPage,
+		Total:      len(items),
+		TotalPages: totalPages,
+	})
+}
+
+// the sort keys of less, sorted, for error messages
+func sortKeys[T any](less map[string]func(a, b T) bool) string {
+	keys := make([]string, 0, len(less))
+	for k := range less {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// reads ?page= (from 1) and ?per_page= (up to maxPerPage)
+func parsePage(q url.Values) (page, perPage int, err error) {
+	page, perPage = 1, defaultPerPage
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page %q must be a number from 1", v)
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > maxPerPage {
+			return 0, 0, fmt.Errorf("per_page %q must be a number from 1 to %d", v, maxPerPage)
+		}
+	}
+	return page, perPage, nil
+}
+
+// encodes v into a buffer first, so a value that can't be encoded is returned
+// as an error before anything is written and becomes a proper 500
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteListPages(t *testing.T) {
+	items := []int{5, 3, 9, 1, 7}
+	less := map[string]func(a, b int) bool{"n": func(a, b int) bool { return a < b }}
+	tests := []struct {
+		query      string
+		status     int
+		data       []int
+		totalPages int
+	}{
+		{"", 200, []int{5, 3, 9, 1, 7}, 1},
+		{"?per_page=2", 200, []int{5, 3}, 3},
+		{"?per_page=2&page=3", 200, []int{7}, 3},
+		{"?per_page=2&page=4", 200, []int{}, 3},
+		{"?page=500000000000000000", 200, []int{}, 1},
+		{"?per_page=100&page=9223372036854775807", 200, []int{}, 1},
+		{"?sort=n&per_page=3", 200, []int{1, 3, 5}, 2},
+		{"?sort=-n&per_page=3&page=2", 200, []int{3, 1}, 2},
+		{"?page=0", 400, nil, 0},
+		{"?page=99999999999999999999", 400, nil, 0},
+		{"?per_page=101", 400, nil, 0},
+		{"?sort=size", 400, nil, 0},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/things"+tt.query, nil)
+		w := httptest.NewRecorder()
+		err := writeList(w, r, append([]int(nil), items...), less)
+		if tt.status != 200 {
+			if e := toAppError(err); err == nil || e.Status != tt.status {
+				t.Errorf("%s: got %v, want status %d", tt.query, err, tt.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		var list struct {
+			Data       []int `json:"data"`
+			TotalPages int   `json:"totalPages"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(list.Data, tt.data) || list.TotalPages != tt.totalPages {
+			t.Errorf("%s: got %v of %d pages, want %v of %d", tt.query, list.Data, list.TotalPages, tt.data, tt.totalPages)
+		}
+	}
+}
+
+func TestWriteJSONEncodingError(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := writeJSON(w, http.StatusOK, map[string]float64{"lat": math.NaN()}); err == nil {
+		t.Fatal("no error for a NaN")
+	}
+	if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Errorf("wrote %q before failing", w.Body.String())
+	}
+
+	// through appHandler the failure becomes a JSON 500
+	h := appHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return writeJSON(w, http.StatusOK, math.Inf(1))
+	})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/x", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"status":500`) {
+		t.Errorf("got %d %q, want a JSON 500", w.Code, w.Body.String())
+	}
+}
+
+func TestNewAPIConcertUpcoming(t *testing.T) {
+	d := Data{A: Artist{Id: 1, Name: "Queen"}}
+	c := Concert{ArtistID: 1, Place: ParsePlace("osaka-japan"), Date: day("2020-01-15")}
+	if got := newAPIConcert(d, c, testNow); !got.Upcoming || got.Date != "2020-01-15" || got.Place != "Osaka, Japan" {
+		t.Errorf("on the day: %+v", got)
+	}
+	if got := newAPIConcert(d, c, testNow.Add(24*time.Hour)); got.Upcoming {
+		t.Errorf("the day after: %+v", got)
+	}
+}
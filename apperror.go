@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// an error a handler returns to end its request with an error response.
+// Message is shown to the user; Err is the internal cause, which is logged
+// but never sent to the client.
+type AppError struct {
+	Status      int
+	Message     string
+	Err         error
+	Suggestions []Data // artists the user may have meant, for a 404 on an artist
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func (e *AppError) Unwrap() error { return e.Err }
+
+// what users see for each status when the handler gives no message of its own
+var statusMessages = map[int]string{
+	http.StatusBadRequest:            "The request was not understood. Please pick an artist from the home page.",
+	http.StatusNotFound:              "This page doesn't exist.",
+	http.StatusMethodNotAllowed:      "This page can't be used that way.",
+	http.StatusRequestEntityTooLarge: "The request was too large.",
+	http.StatusTooManyRequests:       "Too many requests. Please wait a moment and try again.",
+	http.StatusInternalServerError:   "Something went wrong on our side.",
+	http.StatusServiceUnavailable:    "The artist data is not available right now. Please try again shortly.",
+}
+
+// an AppError with the given status; an empty message means the status' default
+func newAppError(status int, message string, cause error) *AppError {
+	if message == "" {
+		message = statusMessages[status]
+	}
+	return &AppError{Status: status, Message: message, Err: cause}
+}
+
+// the catalog couldn't be loaded, so there is nothing to show
+func unavailable(cause error) *AppError {
+	return newAppError(http.StatusServiceUnavailable, "", cause)
+}
+
+// answers with 405 and the Allow header listing the methods the route takes
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) *AppError {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	return newAppError(http.StatusMethodNotAllowed, "", fmt.Errorf("method %s", r.Method))
+}
+
+// a handler that returns its error instead of writing it; ServeHTTP renders it
+type appHandler func(w http.ResponseWriter, r *http.Request) error
+
+func (h appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		renderError(w, r, err)
+	}
+}
+
+// turns any error into an AppError: not-found artists become 404s with
+// suggestions, a cancelled or timed out load 503, and everything else 500
+func toAppError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	var notFound *ArtistNotFoundError
+	if errors.As(err, &notFound) {
+		e := newAppError(http.StatusNotFound, fmt.Sprintf("There is no artist called %q.", notFound.Key), err)
+		e.Suggestions = notFound.Suggestions
+		return e
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return unavailable(err)
+	}
+	return newAppError(http.StatusInternalServerError, "", err)
+}
+
+// the one place error responses are written. the cause is logged; the client
+// gets the public message, as JSON for API clients and error.html otherwise.
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	e := toAppError(err)
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, e)
+
+	if wantsJSON(r) {
+		body := apiErrorBody{Error: apiErrorDetail{Status: e.Status, Message: e.Message}}
+		if err := writeJSON(w, e.Status, body); err != nil {
+			log.Printf("writing error response: %v", err)
+		}
+		return
+	}
+
+	view := errorView{Status: e.Status, StatusText: http.StatusText(e.Status), Message: e.Message, Suggestions: e.Suggestions}
+	var buf bytes.Buffer
+	tErr := errors.New("templates not loaded")
+	if templates != nil {
+		var t *template.Template
+		if t, tErr = templates.Lookup("error.html"); tErr == nil {
+			tErr = t.ExecuteTemplate(&buf, "layout", view)
+		}
+	}
+	if tErr != nil {
+		// the error page itself is broken: fall back to plain text
+		log.Printf("rendering error.html: %v", tErr)
+		http.Error(w, fmt.Sprintf("Error %d: %s", e.Status, e.Message), e.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(e.Status)
+	w.Write(buf.Bytes())
+}
+
+// reports whether the client should get errors as JSON: API routes always,
+// other routes when the Accept header asks for JSON over HTML
+func wantsJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// what error.html shows
+type errorView struct {
+	Status      int
+	StatusText  string // e.g. "Not Found"
+	Message     string
+	Suggestions []Data
+}
+
+// executes the named page through the layout with data and writes the result. rendering into
+// a buffer first means a failing template becomes a clean 500 instead of a
+// half written page.
+func render(w http.ResponseWriter, name string, data interface{}) error {
+	t, err := templates.Lookup(name)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// lowercases name and joins its words with "-": "AC/DC" becomes "ac-dc"
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// reports whether s is all digits, i.e. an artist id rather than a slug
+func isID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// the canonical address of the artist's page
+func (d Data) URL() string {
+	return "/artist/" + d.Slug
+}
+
+// serves GET /artist/{id} and /artist/{slug}
+func artistPage(w http.ResponseWriter, r *http.Request) error {
+	key := strings.TrimPrefix(r.URL.Path, "/artist/")
+	if key == "" || strings.Contains(key, "/") {
+		return newAppError(http.StatusNotFound, "", nil)
+	}
+	snap, err := catalog.Get(r.Context()) // gets the collected data from the catalog
+	if err != nil {                       // the API hasn't been loaded successfully yet
+		return unavailable(err)
+	}
+	d, err := lookupArtist(snap, key)
+	if err != nil {
+		return err // rendered as a 404 with the closest artists
+	}
+	return render(w, "artistPage.html", newArtistView(d, clock.Now()))
+}
+
+// what artistPage.html shows
+type artistView struct {
+	Data
+	Map      *concertMap       // nil when the artist has no concerts
+	Upcoming []upcomingConcert // soonest first
+	Past     int               // number of concerts before today
+}
+
+// a concert still to come, with how long until it
+type upcomingConcert struct {
+	Concert
+	Countdown string // "tomorrow", "in 12 days"
+}
+
+func newArtistView(d Data, now time.Time) artistView {
+	upcoming, past := splitUpcoming(d.Concerts, now)
+	v := artistView{Data: d, Map: newConcertMap(d.Concerts), Past: len(past)}
+	for _, c := range upcoming {
+		v.Upcoming = append(v.Upcoming, upcomingConcert{Concert: c, Countdown: countdown(c.DaysUntil(now))})
+	}
+	return v
+}
+
+// serves the old /artistInfo form, which posts the artist's name as
+// ArtistName, by redirecting to the artist's canonical page
+func legacyArtistPage(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return newAppError(http.StatusRequestEntityTooLarge, "", err)
+		}
+		return newAppError(http.StatusBadRequest, "", err)
+	}
+	value := r.FormValue("ArtistName") // value variable stores the artist name as a form value
+	if value == "" {                   // checks if value is empty
+		return newAppError(http.StatusBadRequest, "", errors.New("no ArtistName"))
+	}
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	for _, d := range snap.Data {
+		if d.A.Name == value {
+			// 303 so the browser follows with a GET, whatever the form's method
+			http.Redirect(w, r, d.URL(), http.StatusSeeOther)
+			return nil
+		}
+	}
+	return notFound(snap, value)
+}
+
+// the most "did you mean" suggestions a 404 lists
+const maxNotFoundSuggestions = 3
+
+// no artist matched Key. Suggestions holds the closest names, best first.
+type ArtistNotFoundError struct {
+	Key         string
+	Suggestions []Data
+}
+
+func (e *ArtistNotFoundError) Error() string {
+	return fmt.Sprintf("no artist %q", e.Key)
+}
+
+// finds the artist by id or slug. when none matches the error is an
+// *ArtistNotFoundError with the closest artists.
+func lookupArtist(snap *Snapshot, key string) (Data, error) {
+	var d Data
+	var ok bool
+	if isID(key) {
+		// an id too big for a uint matches no artist
+		if id, err := strconv.ParseUint(key, 10, 0); err == nil {
+			d, ok = snap.Artist(uint(id))
+		}
+	} else {
+		d, ok = snap.ArtistBySlug(key)
+	}
+	if !ok {
+		return Data{}, notFound(snap, key)
+	}
+	return d, nil
+}
+
+func notFound(snap *Snapshot, key string) *ArtistNotFoundError {
+	return &ArtistNotFoundError{Key: key, Suggestions: closestArtists(snap.Data, key, maxNotFoundSuggestions)}
+}
+
+// returns up to n artists whose name or slug is closest to query by edit
+// distance, ignoring case. names too different to be a typo are left out.
+func closestArtists(data []Data, query string, n int) []Data {
+	query = strings.ToLower(query)
+	type candidate struct {
+		d    Data
+		dist int
+	}
+	var candidates []candidate
+	for _, d := range data {
+		dist := editDistance(query, strings.ToLower(d.A.Name))
+		if s := editDistance(query, d.Slug); s < dist {
+			dist = s
+		}
+		// allow about one typo per three characters
+		if dist <= len([]rune(d.A.Name))/3+1 {
+			candidates = append(candidates, candidate{d, dist})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+	var closest []Data
+	for i := 0; i < len(candidates) && i < n; i++ {
+		closest = append(closest, candidates[i].d)
+	}
+	return closest
+}
+
+// the Levenshtein distance between a and b: how many single character
+// insertions, deletions or substitutions turn one into the other
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, n := range rest {
+		if n < first {
+			first = n
+		}
+	}
+	return first
+}
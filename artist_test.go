@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"queen", "queen", 0},
+		{"queen", "", 5},
+		{"", "abba", 4},
+		{"qeen", "queen", 1},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"motörhead", "motorhead", 1}, // runes, not bytes
+		{"acdc", "ac/dc", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestClosestArtists(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"qeen", []uint{1}},
+		{"QUEEN", []uint{1}},
+		// "Queen" allows 5/3+1 = 2 typos
+		{"quuun", []uint{1}},
+		{"quuux", nil},
+		{"pink-floid", []uint{2}}, // the slug
+		{"pink floid", []uint{2}},
+		{"mercury", []uint{3}},
+		{"metallica", nil},
+	}
+	data := testCatalog()
+	for _, tt := range tests {
+		var ids []uint
+		for _, d := range closestArtists(data, tt.query, maxNotFoundSuggestions) {
+			ids = append(ids, d.A.Id)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("closestArtists(%q) = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
+
+func TestClosestArtistsRankAndCap(t *testing.T) {
+	var data []Data
+	for i, name := range []string{"Abbe", "Abca", "Abba", "Xyzw", "Abbc", "Abbd"} {
+		data = append(data, Data{A: Artist{Id: uint(i + 1), Name: name}, Slug: strings.ToLower(name)})
+	}
+	var names []string
+	for _, d := range closestArtists(data, "abba", maxNotFoundSuggestions) {
+		names = append(names, d.A.Name)
+	}
+	// the exact match first, then the closest in catalog order, maxNotFoundSuggestions in all
+	if want := []string{"Abba", "Abbe", "Abca"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("closest to abba = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// fingerprinted URLs never change content, so browsers may keep them for a year.
+// logical URLs ("/static/css/site.css") keep working but must be revalidated.
+const (
+	immutableCache  = "public, max-age=31536000, immutable"
+	revalidateCache = "no-cache"
+)
+
+// content types by extension, so they don't depend on the host's mime.types
+var assetTypes = map[string]string{
+	".css":   "text/css; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".ico":   "image/x-icon",
+	".woff2": "font/woff2",
+	".json":  "application/json",
+	".txt":   "text/plain; charset=utf-8",
+}
+
+// one static file
+type asset struct {
+	Name        string // logical name, e.g. "css/site.css"
+	Hashed      string // with the content hash, e.g. "css/site.3f2a1b9c0d.css"
+	Hash        string
+	ContentType string
+	content     []byte
+}
+
+// the static files, indexed by logical and by fingerprinted name. in dev mode
+// they are re-read on every use, so an edited file gets a new fingerprint.
+type assetSet struct {
+	fsys   fs.FS
+	dev    bool
+	byName map[string]*asset
+	byHash map[string]*asset
+}
+
+// the assets templates link to, set in run
+var assets *assetSet
+
+// reads and hashes every file in fsys
+func newAssetSet(fsys fs.FS, dev bool) (*assetSet, error) {
+	s := &assetSet{fsys: fsys, dev: dev, byName: make(map[string]*asset), byHash: make(map[string]*asset)}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		a := newAsset(name, content)
+		s.byName[a.Name] = a
+		s.byHash[a.Hashed] = a
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading static files: %w", err)
+	}
+	return s, nil
+}
+
+func newAsset(name string, content []byte) *asset {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:10]
+	ext := path.Ext(name)
+	return &asset{
+		Name:        name,
+		Hashed:      strings.TrimSuffix(name, ext) + "." + hash + ext,
+		Hash:        hash,
+		ContentType: assetType(ext, content),
+		content:     content,
+	}
+}
+
+// the content type for a file extension; unknown ones are sniffed
+func assetType(ext string, content []byte) string {
+	if t, ok := assetTypes[strings.ToLower(ext)]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return http.DetectContentType(content)
+}
+
+// the files as they are now: s itself, or a fresh read from disk in dev mode
+func (s *assetSet) current() (*assetSet, error) {
+	if !s.dev {
+		return s, nil
+	}
+	return newAssetSet(s.fsys, true)
+}
+
+// the fingerprinted URL of a logical asset name, e.g. "css/site.css" becomes
+// "/static/css/site.3f2a1b9c0d.css". an unknown name is an error, so a typo
+// in a template fails the page instead of linking to nothing.
+func (s *assetSet) URL(name string) (string, error) {
+	cur, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	a, ok := cur.byName[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return "", fmt.Errorf("no static file %q", name)
+	}
+	return "/static/" + a.Hashed, nil
+}
+
+// serves /static/: fingerprinted names with a year long cache, logical names
+// with revalidation against the content hash
+func (s *assetSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cur, err := s.current()
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+	cache := immutableCache
+	a, ok := cur.byHash[name]
+	if !ok {
+		cache = revalidateCache
+		a, ok = cur.byName[name]
+	}
+	if !ok {
+		renderError(w, r, newAppError(http.StatusNotFound, "", fmt.Errorf("no static file %q", name)))
+		return
+	}
+	if s.dev {
+		cache = "no-store"
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", a.ContentType)
+	h.Set("Cache-Control", cache)
+	h.Set("ETag", `"`+a.Hash+`"`)
+	h.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, a.Name, time.Time{}, bytes.NewReader(a.content))
+}
+
+// the template function behind {{asset "css/site.css"}}
+func assetURL(name string) (string, error) {
+	if assets == nil {
+		return "", fmt.Errorf("static files not loaded")
+	}
+	return assets.URL(name)
+}
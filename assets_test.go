@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+func TestNewAsset(t *testing.T) {
+	tests := []struct {
+		name, content string
+		hashed        string
+		contentType   string
+	}{
+		{"css/site.css", "body{}", "css/site." + hashOf("body{}") + ".css", "text/css; charset=utf-8"},
+		{"js/search.js", "x()", "js/search." + hashOf("x()") + ".js", "text/javascript; charset=utf-8"},
+		{"favicon.SVG", "<svg/>", "favicon." + hashOf("<svg/>") + ".SVG", "image/svg+xml"},
+		{"robots", "User-agent: *", "robots." + hashOf("User-agent: *"), "text/plain; charset=utf-8"},
+		{"doc.unknownext", "%PDF-1.4", "doc." + hashOf("%PDF-1.4") + ".unknownext", "application/pdf"},
+	}
+	for _, tt := range tests {
+		a := newAsset(tt.name, []byte(tt.content))
+		if a.Name != tt.name || a.Hashed != tt.hashed || a.Hash != hashOf(tt.content) || a.ContentType != tt.contentType {
+			t.Errorf("newAsset(%q) = %+v, want %s as %s", tt.name, a, tt.hashed, tt.contentType)
+		}
+	}
+}
+
+func TestAssetSetURL(t *testing.T) {
+	s, err := newAssetSet(fstest.MapFS{"css/site.css": {Data: []byte("body{}")}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/static/css/site." + hashOf("body{}") + ".css"
+	for _, name := range []string{"css/site.css", "/css/site.css"} {
+		if got, err := s.URL(name); got != want || err != nil {
+			t.Errorf("URL(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if got, err := s.URL("css/sight.css"); err == nil {
+		t.Errorf("URL of an unknown name = %q, want an error", got)
+	}
+
+	// {{asset}} in a template fails the same way
+	defer func(old *assetSet) { assets = old }(assets)
+	assets = s
+	if got, err := assetURL("css/site.css"); got != want || err != nil {
+		t.Errorf("assetURL = %q, %v; want %q", got, err, want)
+	}
+	if got, err := assetURL("css/sight.css"); err == nil {
+		t.Errorf("assetURL of an unknown name = %q, want an error", got)
+	}
+}
+
+func TestAssetSetServeHTTP(t *testing.T) {
+	s, err := newAssetSet(fstest.MapFS{"css/site.css": {Data: []byte("body{}")}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := `"` + hashOf("body{}") + `"`
+	hashed := "/static/css/site." + hashOf("body{}") + ".css"
+	tests := []struct {
+		path, ifNoneMatch string
+		status            int
+		cache, body       string
+	}{
+		{hashed, "", http.StatusOK, immutableCache, "body{}"},
+		{"/static/css/site.css", "", http.StatusOK, revalidateCache, "body{}"},
+		{"/static/css/site.css", etag, http.StatusNotModified, revalidateCache, ""},
+		{hashed, etag, http.StatusNotModified, immutableCache, ""},
+		{"/static/css/site.css", `"0123456789"`, http.StatusOK, revalidateCache, "body{}"},
+		// an old fingerprint is gone rather than served with the new content
+		{"/static/css/site.0123456789.css", "", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", tt.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Cache-Control") != tt.cache {
+			t.Errorf("%s (If-None-Match %s): %d with Cache-Control %q, want %d with %q",
+				tt.path, tt.ifNoneMatch, w.Code, w.Header().Get("Cache-Control"), tt.status, tt.cache)
+		}
+		if tt.status == http.StatusNotFound {
+			continue
+		}
+		if w.Body.String() != tt.body || w.Header().Get("ETag") != etag ||
+			w.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("%s: body %q, headers %v", tt.path, w.Body.String(), w.Header())
+		}
+		if tt.status == http.StatusOK && w.Header().Get("Content-Type") != "text/css; charset=utf-8" {
+			t.Errorf("%s: Content-Type %q", tt.path, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestAssetSetDev(t *testing.T) {
+	fsys := fstest.MapFS{"css/site.css": {Data: []byte("body{}")}}
+	s, err := newAssetSet(fsys, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys["css/site.css"] = &fstest.MapFile{Data: []byte("body{color:red}")}
+	want := "/static/css/site." + hashOf("body{color:red}") + ".css"
+	if got, _ := s.URL("css/site.css"); got != want {
+		t.Errorf("URL after an edit = %q, want %q", got, want)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, want, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" || w.Body.String() != "body{color:red}" {
+		t.Errorf("dev mode served %d %q with Cache-Control %q", w.Code, w.Body.String(), w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the calendar of every concert across artists:
+//
+//	GET /calendar?month=2019-11                the month view, see calendarView
+//	GET /calendar.ics                          every concert as an iCalendar feed
+//	GET /calendar/artist/{id|slug}.ics         the concerts of one artist
+//	GET /calendar/location/{location}.ics      the concerts at one location slug, e.g. "osaka-japan"
+
+// how ?month= is written
+const monthLayout = "2006-01"
+
+// a concert together with its artist
+type calendarEvent struct {
+	Artist Data
+	Concert
+}
+
+// one cell of the month grid
+type calendarDay struct {
+	Date    time.Time
+	InMonth bool // false for the days of the previous and next month that fill the first and last week
+	Events  []calendarEvent
+}
+
+// a month that has concerts, for the month picker
+type calendarMonth struct {
+	Value    string // "2019-11"
+	Label    string // "November 2019"
+	Concerts int
+}
+
+// what calendar.html shows
+type calendarView struct {
+	Month      time.Time // the first day of the month shown
+	Weeks      [][7]calendarDay
+	Concerts   int    // in the month shown
+	Prev, Next string // the closest months before and after with concerts, empty when there are none
+	Months     []calendarMonth
+}
+
+// every concert of the snapshot, by date then artist name
+func allEvents(snap *Snapshot) []calendarEvent {
+	var events []calendarEvent
+	for _, d := range snap.Data {
+		for _, c := range d.Concerts {
+			events = append(events, calendarEvent{Artist: d, Concert: c})
+		}
+	}
+	sortEvents(events)
+	return events
+}
+
+func sortEvents(events []calendarEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.Before(events[j].Date)
+		}
+		return strings.ToLower(events[i].Artist.A.Name) < strings.ToLower(events[j].Artist.A.Name)
+	})
+}
+
+// the first day of t's month
+func monthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// GET /calendar. without ?month= it shows the month of the next concert, or
+// of the last one when they are all past.
+func calendarPage(w http.ResponseWriter, r *http.Request) error {
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	events := allEvents(snap)
+
+	var month time.Time
+	if v := r.URL.Query().Get("month"); v != "" {
+		if month, err = time.Parse(monthLayout, v); err != nil {
+			return newAppError(http.StatusBadRequest, fmt.Sprintf("month %q must be written like 2019-11", v), err)
+		}
+	} else {
+		month = defaultMonth(events, clock.Now())
+	}
+	return render(w, "calendar.html", newCalendarView(events, month))
+}
+
+// the month of the first concert on or after now, else of the last concert
+func defaultMonth(events []calendarEvent, now time.Time) time.Time {
+	if len(events) == 0 {
+		return monthOf(now)
+	}
+	for _, e := range events {
+		if e.Upcoming(now) {
+			return monthOf(e.Date)
+		}
+	}
+	return monthOf(events[len(events)-1].Date)
+}
+
+// lays out the weeks (Monday first) of month with events, which must be sorted
+func newCalendarView(events []calendarEvent, month time.Time) calendarView {
+	v := calendarView{Month: month}
+	byDay := make(map[string][]calendarEvent)
+	counts := make(map[string]int)
+	for _, e := range events {
+		m := e.Date.Format(monthLayout)
+		if counts[m] == 0 {
+			v.Months = append(v.Months, calendarMonth{Value: m, Label: e.Date.Format("January 2006")})
+		}
+		counts[m]++
+		byDay[e.Date.Format("2006-01-02")] = append(byDay[e.Date.Format("2006-01-02")], e)
+	}
+	shown := month.Format(monthLayout)
+	for i := range v.Months {
+		v.Months[i].Concerts = counts[v.Months[i].Value]
+		switch {
+		case v.Months[i].Value < shown:
+			v.Prev = v.Months[i].Value
+		case v.Months[i].Value > shown && v.Next == "":
+			v.Next = v.Months[i].Value
+		}
+	}
+	v.Concerts = counts[shown]
+
+	// back up to the Monday on or before the 1st
+	day := month.AddDate(0, 0, -((int(month.Weekday()) + 6) % 7))
+	for day.Before(month.AddDate(0, 1, 0)) {
+		var week [7]calendarDay
+		for i := range week {
+			week[i] = calendarDay{Date: day, InMonth: day.Month() == month.Month(), Events: byDay[day.Format("2006-01-02")]}
+			day = day.AddDate(0, 0, 1)
+		}
+		v.Weeks = append(v.Weeks, week)
+	}
+	return v
+}
+
+// GET /calendar.ics and /calendar/{artist,location}/{key}.ics
+func calendarFeed(w http.ResponseWriter, r *http.Request) error {
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+
+	var name, file string
+	var events []calendarEvent
+	if r.URL.Path == "/calendar.ics" {
+		name, file, events = "Groupie Tracker concerts", "concerts.ics", allEvents(snap)
+	} else {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/calendar/"), "/")
+		if len(parts) != 2 || !strings.HasSuffix(parts[1], ".ics") {
+			return newAppError(http.StatusNotFound, "", nil)
+		}
+		key := strings.TrimSuffix(parts[1], ".ics")
+		switch parts[0] {
+		case "artist":
+			d, err := lookupArtist(snap, key)
+			if err != nil {
+				return err
+			}
+			name, file = d.A.Name+" concerts", d.Slug+".ics"
+			for _, c := range d.Concerts {
+				events = append(events, calendarEvent{Artist: d, Concert: c})
+			}
+		case "location":
+			for _, e := range allEvents(snap) {
+				if e.Slug == key {
+					events = append(events, e)
+				}
+			}
+			if len(events) == 0 {
+				return newAppError(http.StatusNotFound, fmt.Sprintf("There are no concerts at %q.", key), nil)
+			}
+			name, file = "Concerts in "+events[0].Place.String(), key+".ics"
+		default:
+			return newAppError(http.StatusNotFound, "", nil)
+		}
+		sortEvents(events)
+	}
+
+	base := siteURL(r)
+	ics := make([]icsEvent, len(events))
+	for i, e := range events {
+		ics[i] = icsEvent{
+			UID:      fmt.Sprintf("%d-%s-%s@groupie-tracker", e.ArtistID, e.Slug, e.Date.Format("20060102")),
+			Date:     e.Date,
+			Summary:  e.Artist.A.Name + " in " + e.City,
+			Location: e.Place.String(),
+			URL:      base + e.Artist.URL(),
+		}
+		if e.TourOpener {
+			ics[i].Description = "Tour opener"
+		}
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	// slugs may hold non-ASCII letters, which FormatMediaType encodes as filename*=
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": file}))
+	return writeICS(w, name, snap.LoadedAt, ics)
+}
+
+// the scheme and host the request was made to, e.g. "http://localhost:8080",
+// for the absolute links feeds need
+func siteURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCalendarFeedFilename(t *testing.T) {
+	defer func(old *Catalog) { catalog = old }(catalog)
+	catalog = NewCatalog(func(context.Context) (*Snapshot, error) {
+		concert := func(id uint) []Concert {
+			return []Concert{{ArtistID: id, Place: ParsePlace("berlin-germany"), Date: day("2020-02-01")}}
+		}
+		return newSnapshot([]Data{
+			{A: Artist{Id: 1, Name: "Queen"}, Concerts: concert(1)},
+			{A: Artist{Id: 2, Name: "Motörhead"}, Concerts: concert(2)},
+		}, JoinReport{}), nil
+	}, time.Second)
+
+	tests := []struct {
+		path, header, file string
+	}{
+		{"/calendar.ics", "inline; filename=concerts.ics", "concerts.ics"},
+		{"/calendar/artist/queen.ics", "inline; filename=queen.ics", "queen.ics"},
+		{"/calendar/artist/" + url.PathEscape("motörhead") + ".ics", "inline; filename*=utf-8''mot%C3%B6rhead.ics", "motörhead.ics"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		appHandler(calendarFeed).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		header := w.Header().Get("Content-Disposition")
+		if w.Code != http.StatusOK || header != tt.header {
+			t.Errorf("%s: %d with Content-Disposition %q, want %q", tt.path, w.Code, header, tt.header)
+			continue
+		}
+		// what a client reads back is the slug, not a Go escape
+		if _, params, err := mime.ParseMediaType(header); err != nil || params["filename"] != tt.file {
+			t.Errorf("%s: parsed filename %q, %v; want %q", tt.path, params["filename"], err, tt.file)
+		}
+	}
+}
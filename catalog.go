@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// one consistent version of the API data. a snapshot is never modified after
+// it is built: a refresh builds a new one and swaps it in, so a handler that
+// got a snapshot keeps seeing the same data however long it runs.
+// callers must not modify the slices and maps it holds.
+type Snapshot struct {
+	Data     []Data     // one entry per artist, in upstream order
+	Report   JoinReport // artists that were missing a record
+	Geo      GeoReport  // locations the gazetteer couldn't place
+	LoadedAt time.Time
+
+	byID   map[uint]int   // artist id -> index in Data
+	bySlug map[string]int // Data.Slug -> index in Data
+}
+
+// builds a snapshot around data, which must not be modified afterwards.
+// it gives every artist its slug, adding the id when two names slugify alike.
+func newSnapshot(data []Data, report JoinReport) *Snapshot {
+	s := &Snapshot{
+		Data:     data,
+		Report:   report,
+		LoadedAt: time.Now(),
+		byID:     make(map[uint]int, len(data)),
+		bySlug:   make(map[string]int, len(data)),
+	}
+	for i := range data {
+		slug := slugify(data[i].A.Name)
+		if _, taken := s.bySlug[slug]; taken || slug == "" || isID(slug) {
+			slug = strings.Trim(slug+"-"+strconv.Itoa(int(data[i].A.Id)), "-")
+		}
+		data[i].Slug = slug
+		s.byID[data[i].A.Id] = i
+		s.bySlug[slug] = i
+	}
+	return s
+}
+
+// returns the data of the artist with the given id
+func (s *Snapshot) Artist(id uint) (Data, bool) {
+	i, ok := s.byID[id]
+	if !ok {
+		return Data{}, false
+	}
+	return s.Data[i], true
+}
+
+// returns the data of the artist with the given slug
+func (s *Snapshot) ArtistBySlug(slug string) (Data, bool) {
+	i, ok := s.bySlug[slug]
+	if !ok {
+		return Data{}, false
+	}
+	return s.Data[i], true
+}
+
+// keeps the combined API data in memory so pages are served without
+// calling the API, and refreshes it in the background.
+// a failed refresh keeps the last good snapshot.
+type Catalog struct {
+	load    func(context.Context) (*Snapshot, error) // fetches a fresh snapshot
+	timeout time.Duration                            // deadline for one call to load
+	loading chan struct{}                            // holds a token while a load runs, so loads never overlap
+
+	current atomic.Pointer[Snapshot] // nil until a load succeeds
+}
+
+// the catalog every handler reads from, created in main
+var catalog *Catalog
+
+// creates an empty catalog filled by load, which gets at most timeout per call.
+// call Refresh before serving.
+func NewCatalog(load func(context.Context) (*Snapshot, error), timeout time.Duration) *Catalog {
+	return &Catalog{load: load, timeout: timeout, loading: make(chan struct{}, 1)}
+}
+
+// fetches the data again. the current snapshot is only replaced when the load succeeds.
+// the load is abandoned when ctx is done or the catalog's timeout passes.
+func (c *Catalog) Refresh(ctx context.Context) error {
+	if err := c.lock(ctx); err != nil {
+		return err
+	}
+	defer c.unlock()
+	return c.refreshLocked(ctx)
+}
+
+// waits for any running load to finish, or for ctx to be done
+func (c *Catalog) lock(ctx context.Context) error {
+	select {
+	case c.loading <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Catalog) unlock() { <-c.loading }
+
+// does the work of Refresh; the caller holds the loading token
+func (c *Catalog) refreshLocked(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	snap, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	c.current.Store(snap)
+	return nil
+}
+
+// returns the current snapshot, or nil if no load has succeeded yet
+func (c *Catalog) Snapshot() *Snapshot {
+	return c.current.Load()
+}
+
+// returns the current snapshot. if nothing has been loaded yet it tries a load
+// itself, tied to ctx so a client that goes away cancels the API calls.
+func (c *Catalog) Get(ctx context.Context) (*Snapshot, error) {
+	if snap := c.Snapshot(); snap != nil {
+		return snap, nil
+	}
+	if err := c.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+	// another request may have finished a load while this one waited
+	if snap := c.Snapshot(); snap != nil {
+		return snap, nil
+	}
+	if err := c.refreshLocked(ctx); err != nil {
+		return nil, err
+	}
+	return c.Snapshot(), nil
+}
+
+// refreshes the catalog every interval until ctx is done, which also
+// cancels a refresh in progress. errors are logged and the previous
+// snapshot stays in place.
+func (c *Catalog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("catalog refresh failed: %v (serving data from %s)\n", err, c.loadedAtString())
+			}
+		}
+	}
+}
+
+// formats the current snapshot's load time for log messages
+func (c *Catalog) loadedAtString() string {
+	if snap := c.Snapshot(); snap != nil {
+		return snap.LoadedAt.Format(time.RFC3339)
+	}
+	return "never"
+}
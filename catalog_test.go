@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// a load function that builds numbered snapshots and checks loads never overlap
+type fakeLoader struct {
+	calls   atomic.Int64
+	running atomic.Int64
+	overlap atomic.Bool
+	fail    atomic.Bool
+}
+
+func (l *fakeLoader) load(ctx context.Context) (*Snapshot, error) {
+	if l.running.Add(1) > 1 {
+		l.overlap.Store(true)
+	}
+	defer l.running.Add(-1)
+	n := l.calls.Add(1)
+	if l.fail.Load() {
+		return nil, errors.New("upstream down")
+	}
+	data := make([]Data, 3)
+	for i := range data {
+		data[i].A = Artist{Id: uint(i + 1), Name: fmt.Sprintf("Load %d artist %d", n, i+1)}
+	}
+	return newSnapshot(data, JoinReport{}), nil
+}
+
+// readers must always see a whole snapshot from one load, whatever refreshes run meanwhile
+func TestCatalogReadsDuringRefresh(t *testing.T) {
+	var l fakeLoader
+	c := NewCatalog(l.load, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if err := c.Refresh(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				snap, err := c.Get(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				checkSnapshot(t, snap)
+				if s := c.Snapshot(); s != nil {
+					checkSnapshot(t, s)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if l.overlap.Load() {
+		t.Error("loads overlapped")
+	}
+}
+
+// every artist of snap comes from the same load, and the indexes agree with Data
+func checkSnapshot(t *testing.T, snap *Snapshot) {
+	var load int
+	for i, d := range snap.Data {
+		var n, id int
+		if _, err := fmt.Sscanf(d.A.Name, "Load %d artist %d", &n, &id); err != nil {
+			t.Errorf("unexpected artist %q", d.A.Name)
+			return
+		}
+		if i == 0 {
+			load = n
+		} else if n != load {
+			t.Errorf("snapshot mixes loads %d and %d", load, n)
+		}
+		if got, ok := snap.ArtistBySlug(d.Slug); !ok || got.A.Id != d.A.Id {
+			t.Errorf("ArtistBySlug(%q) = %v, %v", d.Slug, got.A.Id, ok)
+		}
+	}
+}
+
+func TestCatalogFailedRefreshKeepsSnapshot(t *testing.T) {
+	var l fakeLoader
+	c := NewCatalog(l.load, time.Second)
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := c.Snapshot()
+	l.fail.Store(true)
+	if err := c.Refresh(context.Background()); err == nil {
+		t.Fatal("refresh succeeded, want an error")
+	}
+	if c.Snapshot() != before {
+		t.Error("failed refresh replaced the snapshot")
+	}
+}
+
+func TestCatalogGetLoadsOnce(t *testing.T) {
+	var l fakeLoader
+	c := NewCatalog(l.load, time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := l.calls.Load(); n != 1 {
+		t.Errorf("%d loads for concurrent first reads, want 1", n)
+	}
+}
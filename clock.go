@@ -0,0 +1,64 @@
+package main
+
+import "time"
+
+// tells the time. pages ask it rather than time.Now so what counts as past
+// or upcoming can be pinned to a date, see -now.
+type Clock interface {
+	Now() time.Time
+}
+
+// the real time
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// always the same time
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// the clock every handler uses, set in run
+var clock Clock = realClock{}
+
+// midnight UTC of now's date. concert dates are UTC midnights, so a concert
+// on or after today(now) hasn't happened yet.
+func today(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// reports whether the concert is today or later
+func (c Concert) Upcoming(now time.Time) bool {
+	return !c.Date.Before(today(now))
+}
+
+// the number of days from today until the concert; negative once it is past
+func (c Concert) DaysUntil(now time.Time) int {
+	return int(c.Date.Sub(today(now)).Hours() / 24)
+}
+
+// "today", "tomorrow" or "in 12 days"
+func countdown(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	}
+	return "in " + pluralize(days, "day")
+}
+
+// splits concerts, sorted by date, into the ones from today on and the ones before
+func splitUpcoming(concerts []Concert, now time.Time) (upcoming, past []Concert) {
+	for i, c := range concerts {
+		if c.Upcoming(now) {
+			return concerts[i:], concerts[:i]
+		}
+	}
+	return nil, concerts
+}
+
+// reports whether the artist has a concert today or later
+func (d Data) HasUpcoming(now time.Time) bool {
+	return len(d.Concerts) > 0 && d.Concerts[len(d.Concerts)-1].Upcoming(now)
+}
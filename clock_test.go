@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 2020-01-15 late in the evening, and a few hours ahead of UTC
+var testNow = fixedClock(time.Date(2020, 1, 15, 23, 30, 0, 0, time.FixedZone("UTC+2", 2*3600))).Now()
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestConcertUpcoming(t *testing.T) {
+	tests := []struct {
+		date     string
+		upcoming bool
+		days     int
+	}{
+		{"2020-01-14", false, -1},
+		{"2020-01-15", true, 0}, // today, even though the evening has begun
+		{"2020-01-16", true, 1},
+		{"2020-01-27", true, 12},
+		{"2019-01-15", false, -365},
+	}
+	for _, tt := range tests {
+		c := Concert{Date: day(tt.date)}
+		if got := c.Upcoming(testNow); got != tt.upcoming {
+			t.Errorf("%s: Upcoming = %v, want %v", tt.date, got, tt.upcoming)
+		}
+		if got := c.DaysUntil(testNow); got != tt.days {
+			t.Errorf("%s: DaysUntil = %d, want %d", tt.date, got, tt.days)
+		}
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	for days, want := range map[int]string{0: "today", 1: "tomorrow", 2: "in 2 days", 12: "in 12 days"} {
+		if got := countdown(days); got != want {
+			t.Errorf("countdown(%d) = %q, want %q", days, got, want)
+		}
+	}
+}
+
+func TestSplitUpcoming(t *testing.T) {
+	dates := func(cs []Concert) []string {
+		var ds []string
+		for _, c := range cs {
+			ds = append(ds, c.Date.Format("2006-01-02"))
+		}
+		return ds
+	}
+	tests := []struct {
+		all, upcoming, past []string
+	}{
+		{nil, nil, nil},
+		{[]string{"2019-05-01", "2020-01-14"}, nil, []string{"2019-05-01", "2020-01-14"}},
+		{[]string{"2020-01-15", "2020-03-01"}, []string{"2020-01-15", "2020-03-01"}, nil},
+		{[]string{"2019-05-01", "2020-01-14", "2020-01-15", "2020-03-01"}, []string{"2020-01-15", "2020-03-01"}, []string{"2019-05-01", "2020-01-14"}},
+	}
+	for _, tt := range tests {
+		var concerts []Concert
+		for _, d := range tt.all {
+			concerts = append(concerts, Concert{Date: day(d)})
+		}
+		upcoming, past := splitUpcoming(concerts, testNow)
+		if !reflect.DeepEqual(dates(upcoming), tt.upcoming) || !reflect.DeepEqual(dates(past), tt.past) {
+			t.Errorf("%v: split into %v and %v, want %v and %v", tt.all, dates(upcoming), dates(past), tt.upcoming, tt.past)
+		}
+	}
+}
+
+func TestFiltersUpcoming(t *testing.T) {
+	withConcerts := func(id uint, dates ...string) Data {
+		d := Data{A: Artist{Id: id}}
+		for _, s := range dates {
+			d.Concerts = append(d.Concerts, Concert{ArtistID: id, Date: day(s)})
+		}
+		return d
+	}
+	data := []Data{
+		withConcerts(1, "2019-05-01", "2020-01-14"),
+		withConcerts(2, "2019-05-01", "2020-01-15"),
+		withConcerts(3),
+		withConcerts(4, "2021-06-01"),
+	}
+
+	f, err := parseFilters(url.Values{"upcoming": {"1"}}, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Upcoming || !f.Active() {
+		t.Fatalf("upcoming=1 parsed as %+v", f)
+	}
+	var ids []uint
+	for _, d := range applyFilters(data, f) {
+		ids = append(ids, d.A.Id)
+	}
+	if want := []uint{2, 4}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("upcoming artists = %v, want %v", ids, want)
+	}
+
+	// a day later, artist 2's last concert is past
+	f, _ = parseFilters(url.Values{"upcoming": {"1"}}, testNow.Add(24*time.Hour))
+	if f.Match(data[1]) {
+		t.Error("artist 2 still upcoming the day after its last concert")
+	}
+	f, _ = parseFilters(url.Values{}, testNow)
+	if f.Upcoming || !f.Match(data[0]) {
+		t.Errorf("no upcoming filter parsed as %+v", f)
+	}
+}
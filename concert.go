@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// how the API writes dates, e.g. "23-08-2019"
+const apiDateLayout = "02-01-2006"
+
+// a concert location as the API spells it, e.g. "north_carolina-usa",
+// split into the names pages show
+type Place struct {
+	Slug    string // as sent by the API
+	City    string // "North Carolina"
+	Region  string // only set when the slug has three parts: city-region-country
+	Country string // "USA"
+	Coord          // from the gazetteer; only meaningful when Located
+	Located bool
+}
+
+// one show of an artist, built from its Relation, Date and Location entries
+type Concert struct {
+	ArtistID uint
+	Place
+	Date       time.Time
+	TourOpener bool // the dates endpoint marks the first show of a tour with "*"
+}
+
+// words that aren't simply capitalised in place names
+var placeWords = map[string]string{
+	"usa": "USA",
+	"uk":  "UK",
+	"uae": "UAE",
+	"del": "del",
+	"de":  "de",
+	"la":  "la",
+	"of":  "of",
+}
+
+// splits a location slug into its display names
+func ParsePlace(slug string) Place {
+	p := Place{Slug: slug}
+	parts := strings.Split(slug, "-")
+	switch len(parts) {
+	case 1:
+		p.City = placeName(parts[0])
+	case 2:
+		p.City, p.Country = placeName(parts[0]), placeName(parts[1])
+	default:
+		p.City = placeName(parts[0])
+		p.Region = placeName(strings.Join(parts[1:len(parts)-1], "_"))
+		p.Country = placeName(parts[len(parts)-1])
+	}
+	return p
+}
+
+// turns "playa_del_carmen" into "Playa del Carmen"
+func placeName(s string) string {
+	words := strings.Split(s, "_")
+	for i, w := range words {
+		if fixed, ok := placeWords[w]; ok && (i > 0 || fixed != w) {
+			words[i] = fixed
+		} else if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// "North Carolina, USA"
+func (p Place) String() string {
+	names := []string{p.City}
+	if p.Region != "" {
+		names = append(names, p.Region)
+	}
+	if p.Country != "" {
+		names = append(names, p.Country)
+	}
+	return strings.Join(names, ", ")
+}
+
+// the country part of the slug, e.g. "new_zealand"; used to group places by country
+func (p Place) CountryCode() string {
+	return p.Slug[strings.LastIndex(p.Slug, "-")+1:]
+}
+
+// builds the concerts of one artist, sorted by date. every location/date pair
+// of the relation becomes a concert; the dates entry tells which are tour
+// openers. dates that don't parse are skipped and returned in bad.
+func buildConcerts(d Data) (concerts []Concert, bad []string) {
+	openers := make(map[string]bool)
+	for _, date := range d.D.Dates {
+		if strings.HasPrefix(date, "*") {
+			openers[strings.TrimPrefix(date, "*")] = true
+		}
+	}
+
+	for slug, dates := range d.R.DatesLocations {
+		place := ParsePlace(slug)
+		for _, date := range dates {
+			date = strings.TrimPrefix(date, "*")
+			t, err := time.Parse(apiDateLayout, date)
+			if err != nil {
+				bad = append(bad, slug+": "+date)
+				continue
+			}
+			concerts = append(concerts, Concert{ArtistID: d.A.Id, Place: place, Date: t, TourOpener: openers[date]})
+		}
+	}
+
+	// map order is random, so ties on the date are broken by location
+	sort.Slice(concerts, func(i, j int) bool {
+		if !concerts[i].Date.Equal(concerts[j].Date) {
+			return concerts[i].Date.Before(concerts[j].Date)
+		}
+		return concerts[i].Slug < concerts[j].Slug
+	})
+	return concerts, bad
+}
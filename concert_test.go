@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePlace(t *testing.T) {
+	tests := []struct {
+		slug string
+		want Place
+		str  string
+		code string
+	}{
+		{"osaka-japan", Place{Slug: "osaka-japan", City: "Osaka", Country: "Japan"}, "Osaka, Japan", "japan"},
+		{"north_carolina-usa", Place{Slug: "north_carolina-usa", City: "North Carolina", Country: "USA"}, "North Carolina, USA", "usa"},
+		{"playa_del_carmen-mexico", Place{Slug: "playa_del_carmen-mexico", City: "Playa del Carmen", Country: "Mexico"}, "Playa del Carmen, Mexico", "mexico"},
+		{"dunedin-new_zealand", Place{Slug: "dunedin-new_zealand", City: "Dunedin", Country: "New Zealand"}, "Dunedin, New Zealand", "new_zealand"},
+		{"del_mar-usa", Place{Slug: "del_mar-usa", City: "Del Mar", Country: "USA"}, "Del Mar, USA", "usa"},
+		{"springfield-illinois-usa", Place{Slug: "springfield-illinois-usa", City: "Springfield", Region: "Illinois", Country: "USA"}, "Springfield, Illinois, USA", "usa"},
+		{"abu_dhabi-united_arab_emirates", Place{Slug: "abu_dhabi-united_arab_emirates", City: "Abu Dhabi", Country: "United Arab Emirates"}, "Abu Dhabi, United Arab Emirates", "united_arab_emirates"},
+		{"london-uk", Place{Slug: "london-uk", City: "London", Country: "UK"}, "London, UK", "uk"},
+		{"nowhere", Place{Slug: "nowhere", City: "Nowhere"}, "Nowhere", "nowhere"},
+	}
+	for _, tt := range tests {
+		got := ParsePlace(tt.slug)
+		if got != tt.want {
+			t.Errorf("ParsePlace(%q) = %+v, want %+v", tt.slug, got, tt.want)
+		}
+		if got.String() != tt.str {
+			t.Errorf("ParsePlace(%q).String() = %q, want %q", tt.slug, got.String(), tt.str)
+		}
+		if got.CountryCode() != tt.code {
+			t.Errorf("ParsePlace(%q).CountryCode() = %q, want %q", tt.slug, got.CountryCode(), tt.code)
+		}
+	}
+}
+
+func TestPlaceName(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"paris":            "Paris",
+		"usa":              "USA",
+		"los_angeles":      "Los Angeles",
+		"la_plata":         "La Plata",
+		"saint_petersburg": "Saint Petersburg",
+		"isle_of_man":      "Isle of Man",
+		"rio_de_janeiro":   "Rio de Janeiro",
+		"new_south_wales":  "New South Wales",
+	}
+	for in, want := range tests {
+		if got := placeName(in); got != want {
+			t.Errorf("placeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildConcerts(t *testing.T) {
+	d := Data{
+		A: Artist{Id: 7},
+		D: Date{Dates: []string{"*20-08-2019", "22-08-2019", "*26-01-2020"}},
+		R: Relation{DatesLocations: map[string][]string{
+			"los_angeles-usa": {"20-08-2019"},
+			"georgia-usa":     {"22-08-2019", "2019-08-23"},
+			"saitama-japan":   {"*26-01-2020"},
+		}},
+	}
+	concerts, bad := buildConcerts(d)
+	date := func(s string) time.Time {
+		t, _ := time.Parse(apiDateLayout, s)
+		return t
+	}
+	want := []Concert{
+		{ArtistID: 7, Place: ParsePlace("los_angeles-usa"), Date: date("20-08-2019"), TourOpener: true},
+		{ArtistID: 7, Place: ParsePlace("georgia-usa"), Date: date("22-08-2019")},
+		{ArtistID: 7, Place: ParsePlace("saitama-japan"), Date: date("26-01-2020"), TourOpener: true},
+	}
+	if !reflect.DeepEqual(concerts, want) {
+		t.Errorf("got %+v\nwant %+v", concerts, want)
+	}
+	if !reflect.DeepEqual(bad, []string{"georgia-usa: 2019-08-23"}) {
+		t.Errorf("bad dates %v", bad)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GroupieTracker's API, used when neither -api nor GROUPIE_API_URL is set
+const defaultAPIBase = "https://groupietrackers.herokuapp.com/api"
+
+// base URL every fetcher builds its endpoint from, set in main from the config
+var apiBase = defaultAPIBase
+
+// settings for the web server, read from command line flags.
+// every flag falls back to an environment variable so the site can be
+// configured without changing how it is started.
+type config struct {
+	APIBase      string        // e.g. https://groupietrackers.herokuapp.com/api
+	Refresh      time.Duration // how often the catalog reloads the API data
+	FetchTimeout time.Duration // deadline for loading all endpoints once
+
+	Host            string        // interface to listen on, empty for all
+	Port            int           // TCP port to listen on
+	Socket          string        // unix socket path; used instead of Host and Port when set
+	ShutdownTimeout time.Duration // how long open requests get to finish on shutdown
+
+	Dev bool      // read templates and static files from disk, reloading changed templates
+	Now time.Time // pretend it is this day when telling past from upcoming concerts; zero for the real time
+}
+
+// how -now is written
+const nowLayout = "2006-01-02"
+
+// parses the command line arguments (without the program name) into a config
+func loadConfig(args []string) (config, error) {
+	var c config
+	fs := flag.NewFlagSet("groupie-tracker", flag.ContinueOnError)
+	fs.StringVar(&c.APIBase, "api", envOr("GROUPIE_API_URL", defaultAPIBase), "base URL of the upstream API (env GROUPIE_API_URL)")
+	fs.DurationVar(&c.Refresh, "refresh", envDuration("GROUPIE_REFRESH", 10*time.Minute), "how often to reload the API data (env GROUPIE_REFRESH)")
+	fs.DurationVar(&c.FetchTimeout, "fetch-timeout", envDuration("GROUPIE_FETCH_TIMEOUT", 10*time.Second), "deadline for one load of the API data (env GROUPIE_FETCH_TIMEOUT)")
+	fs.StringVar(&c.Host, "host", os.Getenv("GROUPIE_HOST"), "interface to listen on, empty for all (env GROUPIE_HOST)")
+	fs.IntVar(&c.Port, "port", envInt("PORT", 8080), "TCP port to listen on (env PORT)")
+	fs.StringVar(&c.Socket, "socket", os.Getenv("GROUPIE_SOCKET"), "listen on this unix socket instead of host:port (env GROUPIE_SOCKET)")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", envDuration("GROUPIE_SHUTDOWN_TIMEOUT", 15*time.Second), "how long open requests get to finish on shutdown (env GROUPIE_SHUTDOWN_TIMEOUT)")
+	fs.BoolVar(&c.Dev, "dev", os.Getenv("GROUPIE_DEV") != "", "read templates and static files from disk and reload changed templates (env GROUPIE_DEV)")
+	now := fs.String("now", os.Getenv("GROUPIE_NOW"), "pretend today is this date, YYYY-MM-DD, to show the fixtures' concerts as upcoming (env GROUPIE_NOW)")
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+	if fs.NArg() > 0 {
+		return c, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	c.APIBase = strings.TrimRight(c.APIBase, "/")
+	u, err := url.Parse(c.APIBase)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return c, fmt.Errorf("invalid API base URL %q: want http(s)://host/path", c.APIBase)
+	}
+	if c.Refresh <= 0 {
+		return c, fmt.Errorf("invalid refresh interval %v: must be positive", c.Refresh)
+	}
+	if c.FetchTimeout <= 0 {
+		return c, fmt.Errorf("invalid fetch timeout %v: must be positive", c.FetchTimeout)
+	}
+	if c.Socket == "" && (c.Port < 1 || c.Port > 65535) {
+		return c, fmt.Errorf("invalid port %d: must be 1-65535", c.Port)
+	}
+	if c.ShutdownTimeout < 0 {
+		return c, fmt.Errorf("invalid shutdown timeout %v: must not be negative", c.ShutdownTimeout)
+	}
+	if *now != "" {
+		if c.Now, err = time.Parse(nowLayout, *now); err != nil {
+			return c, fmt.Errorf("invalid date %q for -now: want YYYY-MM-DD", *now)
+		}
+	}
+	return c, nil
+}
+
+// returns the environment variable key, or fallback when it is unset or empty
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// the TCP address to listen on, e.g. ":8080"
+func (c config) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// like envOr for integers; unset or unparsable values give fallback
+func envInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// like envOr for durations such as "30s" or "10m"; unset or unparsable values give fallback
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return fallback
+}
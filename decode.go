@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// the locations, dates and relation endpoints wrap their entries in {"index": [...]}
+
+type locationsResponse struct {
+	Index []Location `json:"index"`
+}
+
+type datesResponse struct {
+	Index []Date `json:"index"`
+}
+
+type relationResponse struct {
+	Index []Relation `json:"index"`
+}
+
+// unmarshals body into v, which must point to one of the API types.
+// unlike json.Unmarshal it fails when the JSON has a field v doesn't know about
+// or lacks one of v's fields, so schema changes upstream surface as a
+// *DecodeError (wrapping a *FieldError) instead of silently empty values.
+func decodeStrict(endpoint string, body []byte, v interface{}) error {
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return &DecodeError{Endpoint: endpoint, Err: err}
+	}
+	if dec.More() {
+		return &DecodeError{Endpoint: endpoint, Err: fmt.Errorf("unexpected data after the JSON value")}
+	}
+	if err := checkFields(reflect.TypeOf(v).Elem(), generic, "$"); err != nil {
+		return &DecodeError{Endpoint: endpoint, Err: err}
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return &DecodeError{Endpoint: endpoint, Err: err}
+	}
+	return nil
+}
+
+// walks the generic JSON value alongside the Go type it will be decoded into,
+// comparing the keys of every object with the json tags of the matching struct
+func checkFields(t reflect.Type, v interface{}, path string) error {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return checkFields(t.Elem(), v, path)
+	case reflect.Slice:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil // type mismatches are reported by json.Unmarshal
+		}
+		for i, item := range items {
+			if err := checkFields(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for key, item := range obj {
+			if err := checkFields(t.Elem(), item, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		known := make(map[string]bool, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := jsonFieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			known[name] = true
+			item, present := obj[name]
+			if !present {
+				return &FieldError{Path: path + "." + name, Reason: "missing field"}
+			}
+			if err := checkFields(t.Field(i).Type, item, path+"."+name); err != nil {
+				return err
+			}
+		}
+		for key := range obj {
+			if !known[key] {
+				return &FieldError{Path: path + "." + key, Reason: "unknown field"}
+			}
+		}
+	}
+	return nil
+}
+
+// returns the key encoding/json uses for the field, and false for fields it skips
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
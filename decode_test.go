@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		path  string // of the *FieldError, empty when none is expected
+		valid bool
+	}{
+		{"valid", `{"index":[{"id":1,"dates":["*23-08-2019"]}]}`, "", true},
+		{"empty index", `{"index":[]}`, "", true},
+		{"unknown top-level field", `{"index":[],"page":1}`, "$.page", false},
+		{"missing top-level field", `{}`, "$.index", false},
+		{"unknown field in entry", `{"index":[{"id":1,"dates":[],"venue":"x"}]}`, "$.index[0].venue", false},
+		{"missing field in second entry", `{"index":[{"id":1,"dates":[]},{"id":2}]}`, "$.index[1].dates", false},
+		{"wrong type", `{"index":[{"id":"one","dates":[]}]}`, "", false},
+		{"not JSON", `<html>`, "", false},
+		{"trailing data", `{"index":[]} {}`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v datesResponse
+			err := decodeStrict("dates", []byte(tt.body), &v)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || decodeErr.Endpoint != "dates" {
+				t.Fatalf("got %v, want a *DecodeError for dates", err)
+			}
+			var fieldErr *FieldError
+			if got := errors.As(err, &fieldErr); got != (tt.path != "") {
+				t.Fatalf("got %v, want a *FieldError: %v", err, tt.path != "")
+			}
+			if tt.path != "" && fieldErr.Path != tt.path {
+				t.Errorf("field error at %s, want %s", fieldErr.Path, tt.path)
+			}
+		})
+	}
+}
+
+func TestDecodeStrictRelationMap(t *testing.T) {
+	var v relationResponse
+	body := `{"index":[{"id":1,"datesLocations":{"osaka-japan":["28-01-2020"]}}]}`
+	if err := decodeStrict("relation", []byte(body), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"osaka-japan": {"28-01-2020"}}
+	if !reflect.DeepEqual(v.Index[0].DatesLocations, want) {
+		t.Errorf("got %v, want %v", v.Index[0].DatesLocations, want)
+	}
+}
+
+func TestCheckFieldsSkipsUntaggedAndIgnored(t *testing.T) {
+	type entry struct {
+		ID      int `json:"id"`
+		Skipped int `json:"-"`
+		Name    string
+		private int
+	}
+	obj := map[string]interface{}{"id": 1, "Name": "x"}
+	if err := checkFields(reflect.TypeOf(entry{}), obj, "$"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	delete(obj, "Name")
+	var fieldErr *FieldError
+	if err := checkFields(reflect.TypeOf(entry{}), obj, "$"); !errors.As(err, &fieldErr) || fieldErr.Path != "$.Name" || fieldErr.Reason != "missing field" {
+		t.Errorf("got %v, want missing field $.Name", err)
+	}
+}
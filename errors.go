@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// errors returned by the fetchers. each one names the endpoint it came from
+// and wraps the underlying cause, so callers can use errors.As / errors.Is.
+
+// the API could not be reached or the connection broke while reading, e.g.
+// a DNS failure, a refused connection or a timeout
+type NetworkError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s: network error: %v", e.Endpoint, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// the API answered, but not with 200 OK
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected HTTP status %d %s", e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// the API answered 200 OK but the body isn't the JSON we expect
+type DecodeError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: decoding response: %v", e.Endpoint, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// the response has a field our types don't know about, or lacks one they require.
+// returned wrapped in a *DecodeError.
+type FieldError struct {
+	Path   string // e.g. $.index[3].datesLocations
+	Reason string // "missing field" or "unknown field"
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Reason, e.Path)
+}
+
+// one or more endpoints failed while loading the catalog.
+// every failed endpoint is listed with its own error.
+type LoadError struct {
+	Errors map[string]error // keyed by endpoint, e.g. "dates"
+}
+
+func (e *LoadError) Error() string {
+	endpoints := make([]string, 0, len(e.Errors))
+	for endpoint := range e.Errors {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	msgs := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		msgs[i] = e.Errors[endpoint].Error()
+	}
+	return fmt.Sprintf("loading %d of %d endpoints failed: %s", len(endpoints), len(apiEndpoints), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// the four endpoints of the API that make up the catalog
+var apiEndpoints = []string{"artists", "locations", "dates", "relation"}
+
+// client used for every API call. it has no timeout of its own: each load
+// runs under a context with a deadline instead, see Catalog.
+var httpClient = &http.Client{}
+
+// gets apiBase/endpoint and returns the response body. the request is
+// cancelled when ctx is done. failures come back as *NetworkError or *StatusError.
+func fetch(ctx context.Context, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/"+endpoint, nil)
+	if err != nil {
+		return nil, &NetworkError{Endpoint: endpoint, Err: err}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, &NetworkError{Endpoint: endpoint, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &NetworkError{Endpoint: endpoint, Err: err}
+	}
+	return body, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the home page filters, read from the query string so a filtered view is a
+// plain URL that can be shared, e.g.
+//
+//	/?created_min=1990&created_max=2000&album_min=1995&members=1&members=4&location=usa&upcoming=1
+//
+// zero values mean "not filtered".
+type Filters struct {
+	CreatedMin, CreatedMax int    // range of Artist.CreationDate
+	AlbumMin, AlbumMax     int    // range of the year of Artist.FirstAlbum
+	Members                []int  // keep artists with one of these member counts
+	Location               string // part of a concert place, e.g. "japan" or "Osaka, Japan"
+	Upcoming               bool   // keep artists with a concert from today on
+
+	now time.Time // what Upcoming is relative to
+}
+
+// reads the filters from the query string; malformed numbers are an error.
+// now is the time upcoming concerts are counted from.
+func parseFilters(q url.Values, now time.Time) (Filters, error) {
+	f := Filters{now: now}
+	years := []struct {
+		key string
+		dst *int
+	}{
+		{"created_min", &f.CreatedMin},
+		{"created_max", &f.CreatedMax},
+		{"album_min", &f.AlbumMin},
+		{"album_max", &f.AlbumMax},
+	}
+	for _, y := range years {
+		v := strings.TrimSpace(q.Get(y.key))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("%s: %q is not a year", y.key, v)
+		}
+		*y.dst = n
+	}
+	for _, v := range q["members"] {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return f, fmt.Errorf("members: %q is not a member count", v)
+		}
+		f.Members = append(f.Members, n)
+	}
+	f.Location = strings.TrimSpace(q.Get("location"))
+	f.Upcoming = q.Get("upcoming") != ""
+	return f, nil
+}
+
+// reports whether any filter is set
+func (f Filters) Active() bool {
+	return f.CreatedMin != 0 || f.CreatedMax != 0 || f.AlbumMin != 0 || f.AlbumMax != 0 ||
+		len(f.Members) > 0 || f.Location != "" || f.Upcoming
+}
+
+// reports whether n is one of the selected member counts, for the checkboxes
+func (f Filters) HasMembers(n int) bool {
+	for _, m := range f.Members {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// reports whether the artist passes every filter that is set
+func (f Filters) Match(d Data) bool {
+	if !inRange(int(d.A.CreationDate), f.CreatedMin, f.CreatedMax) {
+		return false
+	}
+	if f.AlbumMin != 0 || f.AlbumMax != 0 {
+		year := d.A.FirstAlbumYear()
+		if year == 0 || !inRange(year, f.AlbumMin, f.AlbumMax) {
+			return false
+		}
+	}
+	if len(f.Members) > 0 && !f.HasMembers(len(d.A.Members)) {
+		return false
+	}
+	if f.Location != "" && !playedAt(d, f.Location) {
+		return false
+	}
+	if f.Upcoming && !d.HasUpcoming(f.now) {
+		return false
+	}
+	return true
+}
+
+// min and max of 0 are open ends
+func inRange(n, min, max int) bool {
+	return (min == 0 || n >= min) && (max == 0 || n <= max)
+}
+
+// reports whether one of the artist's concerts took place somewhere matching location
+func playedAt(d Data, location string) bool {
+	location = strings.ToLower(location)
+	for _, c := range d.Concerts {
+		if strings.Contains(strings.ToLower(c.Place.String()), location) || strings.Contains(c.Slug, location) {
+			return true
+		}
+	}
+	return false
+}
+
+// the artists passing f, in catalog order
+func applyFilters(data []Data, f Filters) []Data {
+	if !f.Active() {
+		return data
+	}
+	var kept []Data
+	for _, d := range data {
+		if f.Match(d) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// the year of the artist's first album, or 0 if the API's date doesn't parse
+func (a Artist) FirstAlbumYear() int {
+	t, err := time.Parse(apiDateLayout, a.FirstAlbum)
+	if err != nil {
+		return 0
+	}
+	return t.Year()
+}
+
+// the values the filter form offers, taken from the whole catalog
+type filterOptions struct {
+	YearMin, YearMax int      // earliest creation or first album year, and the latest
+	MemberCounts     []int    // every member count from 1 to the largest band
+	Locations        []string // every concert place, sorted
+}
+
+func newFilterOptions(data []Data) filterOptions {
+	var opts filterOptions
+	maxMembers := 0
+	places := make(map[string]bool)
+	for _, d := range data {
+		for _, year := range []int{int(d.A.CreationDate), d.A.FirstAlbumYear()} {
+			if year == 0 {
+				continue
+			}
+			if opts.YearMin == 0 || year < opts.YearMin {
+				opts.YearMin = year
+			}
+			if year > opts.YearMax {
+				opts.YearMax = year
+			}
+		}
+		if len(d.A.Members) > maxMembers {
+			maxMembers = len(d.A.Members)
+		}
+		for _, c := range d.Concerts {
+			places[c.Place.String()] = true
+		}
+	}
+	for n := 1; n <= maxMembers; n++ {
+		opts.MemberCounts = append(opts.MemberCounts, n)
+	}
+	for place := range places {
+		opts.Locations = append(opts.Locations, place)
+	}
+	sort.Strings(opts.Locations)
+	return opts
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFiltersErrors(t *testing.T) {
+	for _, query := range []string{
+		"created_min=abc",
+		"created_max=1970.5",
+		"album_min=-1",
+		"album_max=19xx",
+		"members=0",
+		"members=4&members=four",
+	} {
+		q, _ := url.ParseQuery(query)
+		if f, err := parseFilters(q, time.Time{}); err == nil {
+			t.Errorf("%q: no error, got %+v", query, f)
+		}
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	q, _ := url.ParseQuery("created_min=+1960+&album_max=2000&members=1&members=4&location=+Osaka+&created_max=")
+	f, err := parseFilters(q, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Filters{CreatedMin: 1960, AlbumMax: 2000, Members: []int{1, 4}, Location: "Osaka"}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("parsed %+v, want %+v", f, want)
+	}
+	if !f.Active() || !f.HasMembers(4) || f.HasMembers(2) {
+		t.Errorf("Active, HasMembers(4), HasMembers(2) = %v, %v, %v", f.Active(), f.HasMembers(4), f.HasMembers(2))
+	}
+	if f, _ := parseFilters(url.Values{}, time.Time{}); f.Active() {
+		t.Errorf("no query parsed as active: %+v", f)
+	}
+}
+
+func TestFiltersMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []uint // ids of the artists kept
+	}{
+		{"", []uint{1, 2, 3}},
+		{"created_min=1966", []uint{1, 3}},
+		{"created_max=1970", []uint{1, 2}},
+		{"created_min=1966&created_max=1980", []uint{1}},
+		{"created_min=1970&created_max=1970", []uint{1}},
+		// Mercury Rev's first album date doesn't parse, so any album filter drops it
+		{"album_min=1960", []uint{1, 2}},
+		{"album_max=1970", []uint{2}},
+		{"album_min=1990", nil},
+		{"members=1&members=4", []uint{1, 3}},
+		{"members=5", []uint{2}},
+		{"members=2", nil},
+		{"location=japan", []uint{1, 3}},
+		{"location=Osaka, Japan", []uint{1}},
+		{"location=LOS ANGELES", []uint{1}},
+		{"location=los_angeles-usa", []uint{1}},
+		{"location=london-uk", []uint{2}},
+		{"location=Atlantis", nil},
+		{"created_min=1960&members=4&location=japan", []uint{1}},
+	}
+	data := testCatalog()
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		f, err := parseFilters(q, time.Time{})
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		var ids []uint
+		for _, d := range applyFilters(data, f) {
+			ids = append(ids, d.A.Id)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%q kept %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
+
+func TestFirstAlbumYear(t *testing.T) {
+	for album, want := range map[string]int{"14-12-1973": 1973, "1973": 0, "": 0} {
+		if got := (Artist{FirstAlbum: album}).FirstAlbumYear(); got != want {
+			t.Errorf("FirstAlbumYear(%q) = %d, want %d", album, got, want)
+		}
+	}
+}
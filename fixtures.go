@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// recorded responses of the upstream API, in exactly the shape it sends them.
+// every endpoint in apiEndpoints is stored as fixtures/<endpoint>.json.
+//
+//go:embed fixtures/*.json
+var fixtureFiles embed.FS
+
+// runs the "fixtures" command: serves the recorded API so the site works offline.
+//
+//	go run . fixtures -addr localhost:8081
+//	go run . -api http://localhost:8081/api
+func runFixtureServer(args []string) error {
+	fs := flag.NewFlagSet("fixtures", flag.ContinueOnError)
+	addr := fs.String("addr", envOr("GROUPIE_FIXTURE_ADDR", "localhost:8081"), "address to serve the fixture API on (env GROUPIE_FIXTURE_ADDR)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	fmt.Printf("Serving fixture API at http://%s/api\n", *addr)
+	return http.ListenAndServe(*addr, fixtureHandler())
+}
+
+// serves /api, /api/<endpoint> and /api/<endpoint>/<id> from the recorded files
+func fixtureHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, r, "api", "")
+	})
+	for _, name := range apiEndpoints {
+		name := name
+		mux.HandleFunc("/api/"+name, func(w http.ResponseWriter, r *http.Request) {
+			serveFixture(w, r, name, "")
+		})
+		mux.HandleFunc("/api/"+name+"/", func(w http.ResponseWriter, r *http.Request) {
+			serveFixture(w, r, name, strings.TrimPrefix(r.URL.Path, "/api/"+name+"/"))
+		})
+	}
+	return mux
+}
+
+// artist images stay on the upstream host: only the API links are recorded
+const fixtureImages = defaultAPIBase + "/images/"
+
+// writes a recorded response, or the single entry with the given id when id is set.
+// the cross-link URLs inside the recording are rewritten to point back at this
+// server; image URLs are left alone, as there are no recorded images.
+func serveFixture(w http.ResponseWriter, r *http.Request, name, id string) {
+	data, err := fixtureFiles.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if id != "" {
+		entry, ok := fixtureEntry(data, id)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data = entry
+	}
+	// the replacer tries fixtureImages first, so image URLs are kept as they are
+	links := strings.NewReplacer(fixtureImages, fixtureImages, defaultAPIBase, "http://"+r.Host+"/api")
+	data = []byte(links.Replace(string(data)))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// finds the entry with the given id in either a plain array (artists)
+// or an {"index": [...]} wrapper (locations, dates, relation)
+func fixtureEntry(data []byte, id string) (json.RawMessage, bool) {
+	var entries []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var wrapper struct {
+			Index []json.RawMessage `json:"index"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, false
+		}
+		entries = wrapper.Index
+	} else if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, false
+	}
+
+	for _, e := range entries {
+		var head struct {
+			Id json.Number `json:"id"`
+		}
+		if json.Unmarshal(e, &head) == nil && head.Id.String() == id {
+			return e, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// starts the fixture API and points the fetchers at it for the rest of the test
+func startFixtureAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(fixtureHandler())
+	t.Cleanup(srv.Close)
+	old := apiBase
+	apiBase = srv.URL + "/api"
+	t.Cleanup(func() { apiBase = old })
+	return srv
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestFixtureHandler(t *testing.T) {
+	srv := startFixtureAPI(t)
+	tests := []struct {
+		path   string
+		status int
+		want   []string // substrings of the body
+		absent []string
+	}{
+		{"/api", 200, []string{`"artists":"` + srv.URL + `/api/artists"`}, []string{defaultAPIBase}},
+		{"/api/artists", 200, []string{`"name":"Queen"`, `"image":"` + defaultAPIBase + `/images/queen.jpeg"`, `"locations":"` + srv.URL + `/api/locations/1"`}, nil},
+		{"/api/artists/3", 200, []string{`"id":3`, `"name":"Pink Floyd"`}, []string{`"name":"Queen"`}},
+		{"/api/relation/2", 200, []string{`"id":2`, `"datesLocations"`}, nil},
+		{"/api/locations", 200, []string{`"index"`, `"osaka-japan"`}, nil},
+		{"/api/artists/99", 404, nil, nil},
+		{"/api/nope", 404, nil, nil},
+	}
+	for _, tt := range tests {
+		status, body := get(t, srv.URL+tt.path)
+		if status != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, status, tt.status)
+			continue
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(body, s) {
+				t.Errorf("GET %s: body lacks %s", tt.path, s)
+			}
+		}
+		for _, s := range tt.absent {
+			if strings.Contains(body, s) {
+				t.Errorf("GET %s: body still contains %s", tt.path, s)
+			}
+		}
+	}
+}
+
+func TestCollectDataFromFixtures(t *testing.T) {
+	startFixtureAPI(t)
+	snap, err := collectData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Data) != 8 {
+		t.Fatalf("got %d artists, want 8", len(snap.Data))
+	}
+	if !snap.Report.Empty() {
+		t.Errorf("join report: %v", snap.Report)
+	}
+	queen, ok := snap.ArtistBySlug("queen")
+	if !ok {
+		t.Fatal("no artist with slug queen")
+	}
+	if len(queen.Concerts) != 8 {
+		t.Errorf("Queen has %d concerts, want 8", len(queen.Concerts))
+	}
+}
+
+// loads the embedded templates and static assets, for rendering pages
+func loadPages(t *testing.T) {
+	t.Helper()
+	var err error
+	if templates, err = newTemplateSet(false); err != nil {
+		t.Fatal(err)
+	}
+	static, err := staticFiles(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assets, err = newAssetSet(static, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serves the whole site from the fixture API, as main does
+func startSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	startFixtureAPI(t)
+	loadPages(t)
+	var err error
+	if gazetteer, err = loadGazetteer(strings.NewReader(gazetteerCSV)); err != nil {
+		t.Fatal(err)
+	}
+	catalog = NewCatalog(collectData, 10*time.Second)
+	site := httptest.NewServer(newServer("", HandleRequests(assets)).Handler)
+	t.Cleanup(site.Close)
+	return site
+}
+
+func TestSiteOffline(t *testing.T) {
+	site := startSite(t)
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/", 200, "Pink Floyd"},
+		{"/artist/queen", 200, "Osaka"},
+		{"/artist/3", 200, "Pink Floyd"},
+		{"/artist/qeen", 404, `href="/artist/queen"`},
+		{"/artist/99999999999999999999999", 404, "99999999999999999999999"},
+		{"/search?q=osaka", 200, "Queen"},
+		{"/calendar?month=2019-11", 200, "SOJA"},
+		{"/calendar/artist/queen.ics", 200, "BEGIN:VCALENDAR"},
+		{"/nearby?q=Paris", 200, "Pink Floyd"},
+		{"/countries", 200, "Japan"},
+		{"/location/osaka-japan", 200, "Queen"},
+		{"/api/v1/artists/1", 200, `"name":"Queen"`},
+		{"/api/v1/artists/99", 404, `"no artist with id 99"`},
+		{"/api/v1/artists/99999999999999999999999/tours", 404, `"no artist with id 99999999999999999999999"`},
+	}
+	for _, tt := range tests {
+		status, body := get(t, site.URL+tt.path)
+		if status != tt.status || !strings.Contains(body, tt.want) {
+			t.Errorf("GET %s: status %d, want %d with %q", tt.path, status, tt.status, tt.want)
+		}
+	}
+
+	var list apiList
+	_, body := get(t, site.URL+"/api/v1/artists")
+	if err := json.Unmarshal([]byte(body), &list); err != nil || list.Total != 8 {
+		t.Errorf("GET /api/v1/artists: total %d, err %v", list.Total, err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the functions every template can call
+var templateFuncs = template.FuncMap{
+	"formatDate":  formatDate,
+	"formatKm":    formatKm,
+	"pluralize":   pluralize,
+	"slugify":     slugify,
+	"countryName": countryName,
+	"join":        strings.Join,
+	"asset":       assetURL,
+}
+
+// formats a concert date the way pages show it, e.g. "23 Aug 2019"
+func formatDate(t time.Time) string {
+	return t.Format("02 Jan 2006")
+}
+
+// "1 member", "3 members"; an irregular plural can be given: pluralize 2 "city" "cities"
+func pluralize(n int, singular string, plural ...string) string {
+	word := singular
+	if n != 1 {
+		if len(plural) > 0 {
+			word = plural[0]
+		} else {
+			word = singular + "s"
+		}
+	}
+	return strconv.Itoa(n) + " " + word
+}
+
+// the display name of a country as written in location slugs: "new_zealand" is "New Zealand"
+func countryName(code string) string {
+	return placeName(code)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the bundled gazetteer: location slug -> coordinates, so places are
+// resolved without calling a geocoding service
+//
+//go:embed gazetteer/places.csv
+var gazetteerCSV string
+
+// a point on earth in decimal degrees
+type Coord struct {
+	Lat float64
+	Lon float64
+}
+
+// maps location slugs to coordinates
+type Gazetteer struct {
+	places map[string]Coord
+}
+
+// the gazetteer concerts are located with, set in run
+var gazetteer *Gazetteer
+
+// reads "slug,lat,lon" lines. the first line is a header; lines starting
+// with "#" are comments.
+func loadGazetteer(r io.Reader) (*Gazetteer, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 3
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading gazetteer: %w", err)
+	}
+	g := &Gazetteer{places: make(map[string]Coord, len(records))}
+	for i, rec := range records {
+		if i == 0 {
+			continue // header
+		}
+		lat, errLat := strconv.ParseFloat(rec[1], 64)
+		lon, errLon := strconv.ParseFloat(rec[2], 64)
+		if errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("gazetteer: bad coordinates for %q: %s,%s", rec[0], rec[1], rec[2])
+		}
+		g.places[strings.ToLower(rec[0])] = Coord{Lat: lat, Lon: lon}
+	}
+	return g, nil
+}
+
+// the coordinates of a location slug. a city-region-country slug that
+// isn't listed falls back to city-country.
+func (g *Gazetteer) Lookup(slug string) (Coord, bool) {
+	if g == nil {
+		return Coord{}, false
+	}
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if c, ok := g.places[slug]; ok {
+		return c, true
+	}
+	if parts := strings.Split(slug, "-"); len(parts) > 2 {
+		c, ok := g.places[parts[0]+"-"+parts[len(parts)-1]]
+		return c, ok
+	}
+	return Coord{}, false
+}
+
+// location slugs the gazetteer doesn't know, with the artists that play there
+type GeoReport struct {
+	Unresolved map[string][]uint
+}
+
+// reports whether every location was resolved
+func (r GeoReport) Empty() bool {
+	return len(r.Unresolved) == 0
+}
+
+func (r GeoReport) String() string {
+	slugs := make([]string, 0, len(r.Unresolved))
+	for slug, ids := range r.Unresolved {
+		slugs = append(slugs, fmt.Sprintf("%s (artists %v)", slug, ids))
+	}
+	sort.Strings(slugs)
+	return fmt.Sprintf("%d unresolved locations: %s", len(slugs), strings.Join(slugs, ", "))
+}
+
+// sets the coordinates of every concert place and checks every entry of the
+// locations endpoint. what g can't resolve is left unlocated and reported.
+func geocode(g *Gazetteer, data []Data) GeoReport {
+	report := GeoReport{Unresolved: make(map[string][]uint)}
+	unresolved := func(slug string, id uint) {
+		ids := report.Unresolved[slug]
+		if len(ids) == 0 || ids[len(ids)-1] != id {
+			report.Unresolved[slug] = append(ids, id)
+		}
+	}
+	for i := range data {
+		for _, slug := range data[i].L.Locations {
+			if _, ok := g.Lookup(slug); !ok {
+				unresolved(slug, data[i].A.Id)
+			}
+		}
+		for j := range data[i].Concerts {
+			c := &data[i].Concerts[j]
+			if c.Coord, c.Located = g.Lookup(c.Slug); !c.Located {
+				unresolved(c.Slug, data[i].A.Id)
+			}
+		}
+	}
+	return report
+}
+
+// the mean radius of the earth in kilometres
+const earthRadiusKm = 6371.0088
+
+// the great-circle distance between a and b in kilometres (haversine formula)
+func distanceKm(a, b Coord) float64 {
+	const rad = math.Pi / 180
+	dLat := (b.Lat - a.Lat) * rad
+	dLon := (b.Lon - a.Lon) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// the place name matched more than one gazetteer entry
+type AmbiguousPlaceError struct {
+	Name       string
+	Candidates []Place
+}
+
+func (e *AmbiguousPlaceError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, p := range e.Candidates {
+		names[i] = p.String()
+	}
+	return fmt.Sprintf("%q could be %s", e.Name, strings.Join(names, " or "))
+}
+
+// finds a place by name: a slug ("paris-france"), a name with its country
+// ("Paris, France" or "paris france") or just the city ("Paris").
+// a city name shared by several entries is an *AmbiguousPlaceError.
+func (g *Gazetteer) Find(name string) (Place, error) {
+	if g == nil {
+		return Place{}, fmt.Errorf("no gazetteer loaded")
+	}
+	// "Mexico City, Mexico" -> "mexico_city-mexico"
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == ',' })
+	for i, w := range words {
+		words[i] = strings.Join(strings.Fields(w), "_")
+	}
+	key := strings.Join(words, "-")
+	if _, ok := g.places[key]; ok {
+		return g.place(key), nil
+	}
+
+	want := slugify(name)
+	var candidates []Place
+	for slug := range g.places {
+		p := ParsePlace(slug)
+		if slugify(p.City) == want || slugify(p.City+" "+p.Country) == want {
+			candidates = append(candidates, g.place(slug))
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Slug < candidates[j].Slug })
+	switch len(candidates) {
+	case 0:
+		return Place{}, fmt.Errorf("unknown place %q", name)
+	case 1:
+		return candidates[0], nil
+	}
+	return Place{}, &AmbiguousPlaceError{Name: name, Candidates: candidates}
+}
+
+// the located Place of a slug the gazetteer holds
+func (g *Gazetteer) place(slug string) Place {
+	p := ParsePlace(slug)
+	p.Coord, p.Located = g.places[slug]
+	return p
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGazetteerCSV = `slug,lat,lon
+# a comment
+london-uk,51.5074,-0.1278
+paris-france,48.8566,2.3522
+north_carolina-usa,35.7596,-79.0193
+`
+
+func testGazetteer(t *testing.T) *Gazetteer {
+	t.Helper()
+	g, err := loadGazetteer(strings.NewReader(testGazetteerCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDistanceKm(t *testing.T) {
+	paris := Coord{Lat: 48.8566, Lon: 2.3522}
+	london := Coord{Lat: 51.5074, Lon: -0.1278}
+	tests := []struct {
+		name string
+		a, b Coord
+		want float64 // km, to within 1
+	}{
+		{"Paris to London", paris, london, 343.6},
+		{"same place", paris, paris, 0},
+		{"New York to Los Angeles", Coord{40.7128, -74.0060}, Coord{34.0522, -118.2437}, 3935.7},
+		{"along the equator across the date line", Coord{0, 179.5}, Coord{0, -179.5}, 111.2},
+		{"pole to pole", Coord{90, 0}, Coord{-90, 0}, math.Pi * earthRadiusKm},
+		{"antipodes", Coord{0, 0}, Coord{0, 180}, math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		got := distanceKm(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 1 {
+			t.Errorf("%s: %.1f km, want %.1f", tt.name, got, tt.want)
+		}
+		if back := distanceKm(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+			t.Errorf("%s: %.1f km one way, %.1f km back", tt.name, got, back)
+		}
+	}
+}
+
+func TestGazetteerLookup(t *testing.T) {
+	g := testGazetteer(t)
+	tests := []struct {
+		slug string
+		want Coord
+		ok   bool
+	}{
+		{"london-uk", Coord{51.5074, -0.1278}, true},
+		{" London-UK ", Coord{51.5074, -0.1278}, true},
+		// city-region-country falls back to city-country
+		{"london-england-uk", Coord{51.5074, -0.1278}, true},
+		{"paris-ile_de_france-france", Coord{48.8566, 2.3522}, true},
+		{"north_carolina-usa", Coord{35.7596, -79.0193}, true},
+		{"springfield-illinois-usa", Coord{}, false},
+		{"london-canada", Coord{}, false},
+		{"london", Coord{}, false},
+		{"", Coord{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := g.Lookup(tt.slug)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%q) = %v, %v; want %v, %v", tt.slug, got, ok, tt.want, tt.ok)
+		}
+	}
+	var none *Gazetteer
+	if _, ok := none.Lookup("london-uk"); ok {
+		t.Error("a nil gazetteer found london-uk")
+	}
+}
+
+func TestLoadGazetteerErrors(t *testing.T) {
+	for _, csv := range []string{
+		"slug,lat,lon\nlondon-uk,51.5\n",
+		"slug,lat,lon\nlondon-uk,north,-0.1\n",
+		"slug,lat,lon\nlondon-uk,91,-0.1\n",
+		"slug,lat,lon\nlondon-uk,51.5,-181\n",
+	} {
+		if _, err := loadGazetteer(strings.NewReader(csv)); err == nil {
+			t.Errorf("no error loading %q", csv)
+		}
+	}
+}
+
+func TestGeocode(t *testing.T) {
+	show := func(id uint, slug string) Concert {
+		return Concert{ArtistID: id, Place: ParsePlace(slug)}
+	}
+	data := []Data{
+		{
+			A:        Artist{Id: 1},
+			L:        Location{Locations: []string{"atlantis-sea", "london-uk", "lyon-france"}},
+			Concerts: []Concert{show(1, "atlantis-sea"), show(1, "london-uk"), show(1, "atlantis-sea")},
+		},
+		{
+			A:        Artist{Id: 2},
+			L:        Location{Locations: []string{"atlantis-sea"}},
+			Concerts: []Concert{show(2, "atlantis-sea"), show(2, "paris-france")},
+		},
+	}
+	report := geocode(testGazetteer(t), data)
+
+	// each unknown slug once per artist, whether from the locations endpoint or a concert
+	want := map[string][]uint{"atlantis-sea": {1, 2}, "lyon-france": {1}}
+	if !reflect.DeepEqual(report.Unresolved, want) {
+		t.Errorf("Unresolved = %v, want %v", report.Unresolved, want)
+	}
+	if report.Empty() {
+		t.Error("report with unresolved places is Empty")
+	}
+	if s := report.String(); s != "2 unresolved locations: atlantis-sea (artists [1 2]), lyon-france (artists [1])" {
+		t.Errorf("String() = %q", s)
+	}
+
+	london := data[0].Concerts[1]
+	if !london.Located || london.Coord != (Coord{51.5074, -0.1278}) {
+		t.Errorf("london-uk concert = %+v", london)
+	}
+	if c := data[1].Concerts[0]; c.Located || c.Coord != (Coord{}) {
+		t.Errorf("atlantis-sea concert = %+v", c)
+	}
+	if report := geocode(testGazetteer(t), data[1:1]); !report.Empty() {
+		t.Errorf("no data reported %v", report)
+	}
+}
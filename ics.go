@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar (RFC 5545) output for the .ics feeds
+
+// the longest a content line may be, in bytes, before it is folded
+const icsLineLength = 75
+
+// one all-day event of a feed
+type icsEvent struct {
+	UID         string // stable across refreshes, so calendar apps update instead of duplicating
+	Date        time.Time
+	Summary     string
+	Location    string
+	Description string
+	URL         string
+}
+
+// writes a VCALENDAR named name holding events. stamp is the DTSTAMP of every
+// event: when the data was loaded.
+func writeICS(w io.Writer, name string, stamp time.Time, events []icsEvent) error {
+	b := bufio.NewWriter(w)
+	line := func(prop, value string) {
+		writeICSLine(b, prop+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Groupie Tracker//Concerts//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", icsText(name))
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", dtstamp)
+		line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", icsText(e.Summary))
+		if e.Location != "" {
+			line("LOCATION", icsText(e.Location))
+		}
+		if e.Description != "" {
+			line("DESCRIPTION", icsText(e.Description))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return b.Flush()
+}
+
+// writes one content line ended by CRLF, folded into continuation lines
+// (starting with a space) so none is longer than icsLineLength bytes.
+// folds never split a UTF-8 sequence.
+func writeICSLine(b *bufio.Writer, s string) {
+	limit := icsLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icsLineLength - 1 // the leading space counts
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
+
+// escapes a TEXT value: backslashes, commas, semicolons and newlines
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icsText(s string) string {
+	return icsEscaper.Replace(s)
+}
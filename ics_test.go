@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteICSLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		lines int // physical lines after folding
+	}{
+		{"short", "SUMMARY:Queen in Osaka", 1},
+		{"exactly the limit", strings.Repeat("a", 75), 1},
+		{"one over", strings.Repeat("a", 76), 2},
+		{"long ascii", strings.Repeat("a", 75+74+74+1), 4},
+		{"two-byte runes", "SUMMARY:" + strings.Repeat("é", 100), 3},
+		{"two-byte rune across the limit", strings.Repeat("a", 74) + "é", 2},
+		{"four-byte runes", strings.Repeat("🎸", 40), 3},
+		{"mixed", "LOCATION:" + strings.Repeat("Zürich 東京 ", 12), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			b := bufio.NewWriter(&buf)
+			writeICSLine(b, tt.in)
+			b.Flush()
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("%q doesn't end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, l := range lines {
+				if len(l) > icsLineLength {
+					t.Errorf("line %d is %d bytes", i, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, l)
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("continuation line %d doesn't start with a space", i)
+				}
+			}
+			if tt.lines != 0 && len(lines) != tt.lines {
+				t.Errorf("folded into %d lines, want %d", len(lines), tt.lines)
+			}
+			// unfolding (RFC 5545 3.1) gives the input back
+			if got := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); got != tt.in {
+				t.Errorf("unfolded to %q", got)
+			}
+		})
+	}
+}
+
+func TestICSText(t *testing.T) {
+	tests := map[string]string{
+		"Osaka, Japan":      `Osaka\, Japan`,
+		"a;b":               `a\;b`,
+		`back\slash`:        `back\\slash`,
+		"two\nlines":        `two\nlines`,
+		"crlf\r\nline":      `crlf\nline`,
+		"nothing to escape": "nothing to escape",
+	}
+	for in, want := range tests {
+		if got := icsText(in); got != want {
+			t.Errorf("icsText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	var buf bytes.Buffer
+	date := time.Date(2019, 8, 23, 0, 0, 0, 0, time.UTC)
+	stamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	err := writeICS(&buf, "Queen concerts", stamp, []icsEvent{
+		{UID: "1-north_carolina-usa-20190823@groupie-tracker", Date: date, Summary: "Queen in North Carolina", Location: "North Carolina, USA"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Queen concerts\r\n",
+		"DTSTAMP:20200102T020405Z\r\n",
+		"DTSTART;VALUE=DATE:20190823\r\n",
+		"DTEND;VALUE=DATE:20190824\r\n",
+		"LOCATION:North Carolina\\, USA\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "DESCRIPTION") || strings.Contains(out, "URL:") {
+		t.Error("empty optional properties were written")
+	}
+	if strings.Count(out, "\n") != strings.Count(out, "\r\n") {
+		t.Error("bare LF line ending")
+	}
+}
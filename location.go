@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// the concerts seen from the places they were played at, inverting the
+// relation data:
+//
+//	GET /location/{location}   every artist and date at one location slug, e.g. "osaka-japan"
+//	GET /country/{code}        every artist and date in one country, e.g. "japan" or "new_zealand"
+//	GET /countries             every country, most concerts first
+
+// the address of the place's page
+func (p Place) URL() string {
+	return "/location/" + p.Slug
+}
+
+// the address of the page of the place's country
+func (p Place) CountryURL() string {
+	return "/country/" + p.CountryCode()
+}
+
+// an artist and the concerts it played at a place, by date
+type placeArtist struct {
+	Artist   Data
+	Concerts []Concert
+}
+
+// the artists with a concert that keep accepts, by name
+func artistsWhere(snap *Snapshot, keep func(Concert) bool) (artists []placeArtist, concerts int) {
+	for _, d := range snap.Data {
+		var kept []Concert
+		for _, c := range d.Concerts {
+			if keep(c) {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) > 0 {
+			artists = append(artists, placeArtist{Artist: d, Concerts: kept})
+			concerts += len(kept)
+		}
+	}
+	sort.SliceStable(artists, func(i, j int) bool {
+		return strings.ToLower(artists[i].Artist.A.Name) < strings.ToLower(artists[j].Artist.A.Name)
+	})
+	return artists, concerts
+}
+
+// what location.html shows
+type locationView struct {
+	Place    Place
+	Artists  []placeArtist
+	Concerts int
+}
+
+// GET /location/{location}
+func locationPage(w http.ResponseWriter, r *http.Request) error {
+	slug := strings.TrimPrefix(r.URL.Path, "/location/")
+	if slug == "" || strings.Contains(slug, "/") {
+		return newAppError(http.StatusNotFound, "", nil)
+	}
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	artists, concerts := artistsWhere(snap, func(c Concert) bool { return c.Slug == slug })
+	if len(artists) == 0 {
+		return newAppError(http.StatusNotFound, fmt.Sprintf("There are no concerts at %q.", slug), nil)
+	}
+	return render(w, "location.html", locationView{
+		Place:    artists[0].Concerts[0].Place,
+		Artists:  artists,
+		Concerts: concerts,
+	})
+}
+
+// a place with how often it was played
+type placeSummary struct {
+	Place    Place
+	Concerts int
+	Artists  int
+}
+
+// a country with how often it was played
+type countrySummary struct {
+	Code     string // as in location slugs, e.g. "new_zealand"
+	Name     string // "New Zealand"
+	Concerts int
+	Artists  int
+	Places   int
+}
+
+// the address of the country's page
+func (c countrySummary) URL() string {
+	return "/country/" + c.Code
+}
+
+// what country.html shows
+type countryView struct {
+	Country countrySummary
+	Cities  []placeSummary // most concerts first
+	Artists []placeArtist
+}
+
+// GET /country/{code}
+func countryPage(w http.ResponseWriter, r *http.Request) error {
+	code := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/country/"))
+	if code == "" || strings.Contains(code, "/") {
+		return newAppError(http.StatusNotFound, "", nil)
+	}
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	inCountry := func(c Concert) bool { return c.Country != "" && c.CountryCode() == code }
+	artists, concerts := artistsWhere(snap, inCountry)
+	if len(artists) == 0 {
+		return newAppError(http.StatusNotFound, fmt.Sprintf("There are no concerts in %q.", code), nil)
+	}
+
+	cities := placeSummaries(artists)
+	return render(w, "country.html", countryView{
+		Country: countrySummary{
+			Code:     code,
+			Name:     artists[0].Concerts[0].Country,
+			Concerts: concerts,
+			Artists:  len(artists),
+			Places:   len(cities),
+		},
+		Cities:  cities,
+		Artists: artists,
+	})
+}
+
+// the places the artists played at, most concerts first
+func placeSummaries(artists []placeArtist) []placeSummary {
+	bySlug := make(map[string]*placeSummary)
+	for _, a := range artists {
+		seen := make(map[string]bool)
+		for _, c := range a.Concerts {
+			p, ok := bySlug[c.Slug]
+			if !ok {
+				p = &placeSummary{Place: c.Place}
+				bySlug[c.Slug] = p
+			}
+			p.Concerts++
+			if !seen[c.Slug] {
+				seen[c.Slug] = true
+				p.Artists++
+			}
+		}
+	}
+	places := make([]placeSummary, 0, len(bySlug))
+	for _, p := range bySlug {
+		places = append(places, *p)
+	}
+	sort.Slice(places, func(i, j int) bool {
+		if places[i].Concerts != places[j].Concerts {
+			return places[i].Concerts > places[j].Concerts
+		}
+		return places[i].Place.String() < places[j].Place.String()
+	})
+	return places
+}
+
+// GET /countries
+func countriesPage(w http.ResponseWriter, r *http.Request) error {
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	byCode := make(map[string]*countrySummary)
+	artistsIn := make(map[string]map[uint]bool)
+	placesIn := make(map[string]map[string]bool)
+	for _, d := range snap.Data {
+		for _, c := range d.Concerts {
+			if c.Country == "" {
+				continue
+			}
+			code := c.CountryCode()
+			s, ok := byCode[code]
+			if !ok {
+				s = &countrySummary{Code: code, Name: c.Country}
+				byCode[code] = s
+				artistsIn[code] = make(map[uint]bool)
+				placesIn[code] = make(map[string]bool)
+			}
+			s.Concerts++
+			artistsIn[code][d.A.Id] = true
+			placesIn[code][c.Slug] = true
+		}
+	}
+	countries := make([]countrySummary, 0, len(byCode))
+	for code, s := range byCode {
+		s.Artists, s.Places = len(artistsIn[code]), len(placesIn[code])
+		countries = append(countries, *s)
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		if countries[i].Concerts != countries[j].Concerts {
+			return countries[i].Concerts > countries[j].Concerts
+		}
+		return countries[i].Name < countries[j].Name
+	})
+	return render(w, "countries.html", countries)
+}
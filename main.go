@@ -1,13 +1,17 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
-	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 )
 
 type Data struct {
@@ -15,6 +19,10 @@ type Data struct {
 	R Relation
 	L Location
 	D Date
+
+	Concerts []Concert // built from R, L and D, sorted by date
+	Tours    []Tour    // Concerts split into tours
+	Slug     string    // unique name for URLs, e.g. "pink-floyd"
 }
 
 type Artist struct {
@@ -24,230 +32,331 @@ type Artist struct {
 	Members      []string `json:"members"`
 	CreationDate uint     `json:"creationDate"`
 	FirstAlbum   string   `json:"firstAlbum"`
+	Locations    string   `json:"locations"`    // URL of this artist's locations entry
+	ConcertDates string   `json:"concertDates"` // URL of this artist's dates entry
+	Relations    string   `json:"relations"`    // URL of this artist's relation entry
 }
 
+// every location, date and relation entry carries the id of the artist it belongs to
+
 type Location struct {
+	Id        uint     `json:"id"`
 	Locations []string `json:"locations"`
+	Dates     string   `json:"dates"` // URL of the matching dates entry
 }
 
 type Date struct {
+	Id    uint     `json:"id"`
 	Dates []string `json:"dates"`
 }
 
 type Relation struct {
+	Id             uint                `json:"id"`
 	DatesLocations map[string][]string `json:"datesLocations"`
 }
 
-var (
-	artistInfo  []Artist                   // slice of artist structs
-	locationMap map[string]json.RawMessage // maps a string key to a json.RawMessage value
-	//RawMessage = byte slice that represents a JSON value, doesn't need to be parsed
-	locationInfo []Location
-	datesMap     map[string]json.RawMessage
-	datesInfo    []Date
-	relationMap  map[string]json.RawMessage
-	relationInfo []Relation
-)
+func ArtistData(ctx context.Context) ([]Artist, error) {
+	// The code will read the data from a JSON response from GroupieTracker's API
 
-// handles 404, 500, 400 errors
-func errorHandler(w http.ResponseWriter, r *http.Request, status int) {
-	w.WriteHeader(status)
-	t, err := template.ParseFiles("error.html")
-	errorMssg := ""
-	if status == http.StatusNotFound { // if status = 404
-		if err != nil {
-			// if it is not giving an error (when there is one),
-			// return a 500 error instead
-			errorHandler(w, r, http.StatusInternalServerError)
-			return
-		}
-		errorMssg = "Error: HTTP status 404"
-		fmt.Println(errorMssg)
-		t.Execute(w, errorMssg)
-	}
-	if status == http.StatusInternalServerError { // if status = 500
-		errorMssg = "Error: HTTP status 500"
-		if err != nil {
-			fmt.Fprint(w, errorMssg)
-		}
-		fmt.Println(errorMssg)
-		t.Execute(w, errorMssg)
-	}
-	if status == http.StatusBadRequest { // if status = 400
-		errorMssg = "Error: HTTP status 400"
-		if err != nil {
-			errorMssg = "Error: HTTP status 500"
-			fmt.Fprint(w, errorMssg)
-		}
-		fmt.Println(errorMssg)
-		t.Execute(w, errorMssg)
+	artistData, err := fetch(ctx, "artists") //grabs list of artists from link, stores the body in artistData
+	if err != nil {
+		return nil, err
 	}
+	var artists []Artist
+	err = decodeStrict("artists", artistData, &artists) //unmarshalls the data from artistData into the slice of artist structs
+	if err != nil {
+		return nil, err
+	}
+	return artists, nil
 }
 
-func ArtistData() []Artist {
-	// The code will read the data from a JSON response from GroupieTracker's API
+func LocationData(ctx context.Context) ([]Location, error) {
+	//  The code will take the JSON response from GroupieTracker and unwrap its "index" list of Location data.
 
-	artist, err := http.Get("https://groupietrackers.herokuapp.com/api/artists") //grabs list of artists from link
+	locationData, err := fetch(ctx, "locations") // gets location data from link, stores in locationData
 	if err != nil {
-		log.Fatal()
+		return nil, err
 	}
-	artistData, err := io.ReadAll(artist.Body) //reads data using ReadAll, stores in artistData
+	var resp locationsResponse
+	err = decodeStrict("locations", locationData, &resp) // unmarshalls locationData into the {"index": [...]} wrapper
 	if err != nil {
-		log.Fatal()
+		return nil, err
 	}
-	json.Unmarshal(artistData, &artistInfo) //unmarshalls the data from artistData into the artistinfo struct
-	return artistInfo
+	return resp.Index, nil
 }
 
-func LocationData() []Location {
-	//  The code will take the JSON response from GroupieTracker and parse it into a map of Location data.
-
-	var bytes []byte                                                                  // empty array of bytes
-	location, err2 := http.Get("https://groupietrackers.herokuapp.com/api/locations") // gets locations from link, stores in location
-	if err2 != nil {
-		log.Fatal()
-	}
-	locationData, err3 := io.ReadAll(location.Body) // reads location data from JSON file, stores in locationData
-	if err3 != nil {
-		log.Fatal()
+func DatesData(ctx context.Context) ([]Date, error) {
+	datesData, err := fetch(ctx, "dates")
+	if err != nil {
+		return nil, err
 	}
-	err := json.Unmarshal(locationData, &locationMap) // unmarshalls locationData, stores in locationMap struct
+	var resp datesResponse
+	err = decodeStrict("dates", datesData, &resp)
 	if err != nil {
-		fmt.Println("error :", err)
+		return nil, err
 	}
-	for _, m := range locationMap { // for every value in locationMap, m is created
-		for _, v := range m { // for every value in m, v is created
-			bytes = append(bytes, v) // each value is appended into the array of bytes from before
-		}
+	return resp.Index, nil
+}
+
+func RelationData(ctx context.Context) ([]Relation, error) {
+	relationData, err := fetch(ctx, "relation")
+	if err != nil {
+		return nil, err
 	}
-	err = json.Unmarshal(bytes, &locationInfo)
+	var resp relationResponse
+	err = decodeStrict("relation", relationData, &resp)
 	if err != nil {
-		fmt.Println("error :", err)
+		return nil, err
 	}
-	return locationInfo
+	return resp.Index, nil
 }
 
-func DatesData() []Date {
-	var bytes []byte
-	dates, err2 := http.Get("https://groupietrackers.herokuapp.com/api/dates")
-	if err2 != nil {
-		log.Fatal()
+func collectData(ctx context.Context) (*Snapshot, error) {
+	// The code is used to collect data about the artist, relation, location and date
+
+	// calls functions from before, all four at once. if any of them fails the
+	// others keep going so every failing endpoint ends up in the LoadError.
+	// each goroutine only writes its own variable, read after wg.Wait.
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		failures  = make(map[string]error)
+		artists   []Artist
+		relations []Relation
+		locations []Location
+		dates     []Date
+	)
+	run := func(endpoint string, load func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := load(ctx); err != nil {
+				mu.Lock()
+				failures[endpoint] = err
+				mu.Unlock()
+			}
+		}()
 	}
-	datesData, err3 := ioutil.ReadAll(dates.Body)
-	if err3 != nil {
-		log.Fatal()
+	run("artists", func(ctx context.Context) (err error) { artists, err = ArtistData(ctx); return })
+	run("relation", func(ctx context.Context) (err error) { relations, err = RelationData(ctx); return })
+	run("locations", func(ctx context.Context) (err error) { locations, err = LocationData(ctx); return })
+	run("dates", func(ctx context.Context) (err error) { dates, err = DatesData(ctx); return })
+	wg.Wait()
+	if len(failures) > 0 {
+		return nil, &LoadError{Errors: failures}
 	}
-	err := json.Unmarshal(datesData, &datesMap)
-	if err != nil {
-		fmt.Println("error :", err)
+
+	if len(artists) == 0 {
+		return nil, errors.New("no artists received from the API")
+	}
+	data, report := joinData(artists, relations, locations, dates)
+	if !report.Empty() {
+		fmt.Println("incomplete API data:", report)
 	}
-	for _, m := range datesMap {
-		for _, v := range m {
-			bytes = append(bytes, v)
+	for i := range data {
+		var bad []string
+		data[i].Concerts, bad = buildConcerts(data[i])
+		if len(bad) > 0 {
+			fmt.Printf("skipped unparsable concert dates of %s: %v\n", data[i].A.Name, bad)
 		}
 	}
-	err = json.Unmarshal(bytes, &datesInfo)
-	if err != nil {
-		fmt.Println("error :", err)
+	geo := geocode(gazetteer, data)
+	if !geo.Empty() {
+		fmt.Println("geocoding:", geo)
+	}
+	for i := range data {
+		data[i].Tours = buildTours(data[i].Concerts) // after geocoding, for the distances
 	}
-	return datesInfo
+	snap := newSnapshot(data, report)
+	snap.Geo = geo
+	return snap, nil
 }
 
-func RelationData() []Relation {
-	var bytes []byte
-	relation, err2 := http.Get("https://groupietrackers.herokuapp.com/api/relation")
-	if err2 != nil {
-		log.Fatal()
+// artists that have no relation, location or dates entry with their id
+type JoinReport struct {
+	MissingRelation []uint
+	MissingLocation []uint
+	MissingDates    []uint
+}
+
+// reports whether every artist had all three records
+func (r JoinReport) Empty() bool {
+	return len(r.MissingRelation) == 0 && len(r.MissingLocation) == 0 && len(r.MissingDates) == 0
+}
+
+func (r JoinReport) String() string {
+	return fmt.Sprintf("artists without relation %v, without locations %v, without dates %v",
+		r.MissingRelation, r.MissingLocation, r.MissingDates)
+}
+
+// builds one Data per artist, matching the other records by id rather than by
+// position, so the upstream order doesn't matter. an artist with a missing
+// record is still included (with that field left empty) and listed in the report.
+func joinData(artists []Artist, relations []Relation, locations []Location, dates []Date) ([]Data, JoinReport) {
+	relationByID := make(map[uint]Relation, len(relations))
+	for _, rel := range relations {
+		relationByID[rel.Id] = rel
 	}
-	relationData, err3 := ioutil.ReadAll(relation.Body)
-	if err3 != nil {
-		log.Fatal()
+	locationByID := make(map[uint]Location, len(locations))
+	for _, loc := range locations {
+		locationByID[loc.Id] = loc
 	}
-	err := json.Unmarshal(relationData, &relationMap)
-	if err != nil {
-		fmt.Println("error :", err)
+	datesByID := make(map[uint]Date, len(dates))
+	for _, d := range dates {
+		datesByID[d.Id] = d
 	}
 
-	for _, m := range relationMap {
-		for _, v := range m {
-			bytes = append(bytes, v)
+	var report JoinReport
+	data := make([]Data, len(artists))
+	for i, a := range artists {
+		data[i].A = a
+		var ok bool
+		if data[i].R, ok = relationByID[a.Id]; !ok {
+			report.MissingRelation = append(report.MissingRelation, a.Id)
+		}
+		if data[i].L, ok = locationByID[a.Id]; !ok {
+			report.MissingLocation = append(report.MissingLocation, a.Id)
+		}
+		if data[i].D, ok = datesByID[a.Id]; !ok {
+			report.MissingDates = append(report.MissingDates, a.Id)
 		}
 	}
+	return data, report
+}
+
+// what index.html shows: the artist cards, and the search or filters that picked them
+type homeView struct {
+	Artists     []Data
+	Query       string
+	Suggestions []Suggestion
+	Filters     Filters
+	Options     filterOptions
+}
 
-	err = json.Unmarshal(bytes, &relationInfo)
+func homePage(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path != "/" {
+		return newAppError(http.StatusNotFound, "", nil)
+	}
+	snap, err := catalog.Get(r.Context()) // served from memory, the catalog refreshes itself in the background
+	if err != nil {                       // the API hasn't been loaded successfully yet
+		return unavailable(err)
+	}
+	filters, err := parseFilters(r.URL.Query(), clock.Now()) // filters come from the URL so they can be shared
 	if err != nil {
-		fmt.Println("error :", err)
+		return newAppError(http.StatusBadRequest, "Invalid filter: "+err.Error(), err)
 	}
-	return relationInfo
+	return render(w, "index.html", homeView{
+		Artists: applyFilters(snap.Data, filters),
+		Filters: filters,
+		Options: newFilterOptions(snap.Data),
+	})
 }
 
-func collectData() []Data {
-	// The code is used to collect data about the artist, relation, location and date
-
-	// calls functions from before
-	ArtistData()
-	RelationData()
-	LocationData()
-	DatesData()
+// collection of webpage handlers
+func HandleRequests(static *assetSet) *http.ServeMux {
+	read := allowMethods(http.MethodGet, http.MethodHead)
+	mux := http.NewServeMux()
+	mux.Handle("/", read(appHandler(homePage)))
+	mux.Handle("/artistInfo", allowMethods(http.MethodGet, http.MethodHead, http.MethodPost)(appHandler(legacyArtistPage)))
+	mux.Handle("/artist/", read(appHandler(artistPage)))
+	mux.Handle("/search", read(appHandler(searchPage)))
+	mux.Handle("/search/suggestions", read(appHandler(searchSuggestions)))
+	mux.Handle("/location/", read(appHandler(locationPage)))
+	mux.Handle("/country/", read(appHandler(countryPage)))
+	mux.Handle("/countries", read(appHandler(countriesPage)))
+	mux.Handle("/nearby", read(appHandler(nearbyPage)))
+	mux.Handle("/calendar", read(appHandler(calendarPage)))
+	mux.Handle("/calendar.ics", read(appHandler(calendarFeed)))
+	mux.Handle("/calendar/", read(appHandler(calendarFeed)))
+	mux.Handle("/api/v1/", appHandler(apiHandler)) // checks methods itself, to answer in JSON
+	mux.Handle("/static/", read(static))
+	return mux
+}
 
-	dataData := make([]Data, len(artistInfo)) // an empty array of Data objects that will be used to temporarily store names, locations etc.
-	for i := 0; i < len(artistInfo); i++ {    // iterates through artistInfo values
-		dataData[i].A = artistInfo[i]   // uses i to assign values from artistInfo to the A field in dataData
-		dataData[i].R = relationInfo[i] // same is done for R and relationInfo, L for locationInfo etc.
-		dataData[i].L = locationInfo[i]
-		dataData[i].D = datesInfo[i]
+func main() {
+	// "fixtures" serves the recorded API instead of the website
+	if len(os.Args) > 1 && os.Args[1] == "fixtures" {
+		if err := runFixtureServer(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	return dataData
-}
 
-func homePage(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" && r.URL.Path != "/artistInfo" {
-		errorHandler(w, r, http.StatusNotFound)
+	cfg, err := loadConfig(os.Args[1:])
+	if err == flag.ErrHelp {
 		return
 	}
-	data := ArtistData()
-	t, err := template.ParseFiles("index.html") // parse thru data
 	if err != nil {
-		errorHandler(w, r, http.StatusInternalServerError)
-		return
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	t.Execute(w, data) // executes template
+	apiBase = cfg.APIBase
+	os.Exit(run(cfg))
 }
 
-func artistPage(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/artistInfo" && r.URL.Path != "/" { // checks if URL ends with 'artistInfo'
-		errorHandler(w, r, http.StatusNotFound)
-		return
+// runs the site until SIGINT or SIGTERM and returns the exit code
+func run(cfg config) int {
+	// templates are parsed once here, so a broken one stops the server before it starts
+	var err error
+	if templates, err = newTemplateSet(cfg.Dev); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-	value := r.FormValue("ArtistName") // value variable stores the artist name as a form value
-	if value == "" {                   // checks if value is empty
-		errorHandler(w, r, http.StatusBadRequest)
-		return
+	if gazetteer, err = loadGazetteer(strings.NewReader(gazetteerCSV)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-	a := collectData()                  // calls collectData, stores as a new variable
-	var b Data                          // creates new variable named b
-	for i, ele := range collectData() { // ranges over collectData using i and v
-		if value == ele.A.Name { // checks if value is equal to v (in collectData)
-			// of the A field (Data struct), of Name (Artist struct)
-			b = a[i] // assigns b variable to the collectData element at i
-		}
+	static, err := staticFiles(cfg.Dev)
+	if err == nil {
+		assets, err = newAssetSet(static, cfg.Dev)
 	}
-	t, err := template.ParseFiles("artistPage.html")
 	if err != nil {
-		errorHandler(w, r, http.StatusInternalServerError)
-		return
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if cfg.Dev {
+		fmt.Println("Dev mode: reading templates and static files from disk")
+	}
+	if !cfg.Now.IsZero() {
+		clock = fixedClock(cfg.Now)
+		fmt.Println("Pretending today is", cfg.Now.Format(nowLayout))
 	}
-	t.Execute(w, b) // executes template using data from b
-}
 
-// collection of webpage handlers
-func HandleRequests() {
-	fmt.Println("Fetching server at port 8080...")
-	http.HandleFunc("/", homePage)
-	http.HandleFunc("/artistInfo", artistPage)
-	http.ListenAndServe(":8080", nil)
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// the first signal starts a graceful shutdown; stopping the relay then
+	// restores the default handling, so a second Ctrl-C exits at once
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-func main() {
-	HandleRequests()
+	// listening comes first, so a port in use is reported straight away and
+	// connections are accepted while the API data loads
+	ln, err := listen(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "server:", err)
+		return 1
+	}
+
+	// loads the API data once, then keeps it fresh in the background.
+	// requests that arrive before the first load wait for it in catalog.Get.
+	catalog = NewCatalog(collectData, cfg.FetchTimeout)
+	refreshDone := make(chan struct{})
+	go func() {
+		defer close(refreshDone)
+		if err := catalog.Refresh(ctx); err != nil && ctx.Err() == nil {
+			fmt.Println("initial catalog load failed, will retry:", err)
+		}
+		catalog.Run(ctx, cfg.Refresh)
+	}()
+
+	err = serve(ctx, cfg, ln, HandleRequests(assets))
+	stop() // also stops the refreshes when serve failed on its own
+	<-refreshDone
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "server:", err)
+		return 1
+	}
+	return 0
 }
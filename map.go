@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// the tour map of the artist page, drawn server side as inline SVG by
+// partials/concert_map.html. places are projected equirectangularly, with
+// longitudes shrunk by the cosine of the middle latitude so a region keeps
+// its shape, and the view is fitted around the artist's concerts.
+
+const (
+	mapWidth   = 800
+	mapHeight  = 420
+	mapPadding = 36 // pixels between the outermost markers and the edge
+	mapMinSpan = 8  // degrees; a tour of one region isn't zoomed in further
+)
+
+// grid spacings to pick from, in degrees
+var mapGridSteps = []float64{1, 2, 5, 10, 15, 30, 45, 90}
+
+// what the concert-map partial draws
+type concertMap struct {
+	Width, Height int
+	Markers       []mapMarker
+	Route         string // polyline points of the concerts in date order, "x,y x,y ..."
+	Grid          []mapGridLine
+	Unlocated     []Concert // concerts the gazetteer couldn't place
+}
+
+// one place on the map with the concerts played there
+type mapMarker struct {
+	X, Y     float64
+	Place    Place
+	Concerts []Concert
+	Order    int // 1 for the first place visited, 2 for the next...
+}
+
+// a meridian or parallel with its label
+type mapGridLine struct {
+	X1, Y1, X2, Y2 float64
+	Label          string
+}
+
+// the title of the marker: the place and its dates
+func (m mapMarker) Title() string {
+	dates := make([]string, len(m.Concerts))
+	for i, c := range m.Concerts {
+		dates[i] = formatDate(c.Date)
+	}
+	return m.Place.String() + ": " + strings.Join(dates, ", ")
+}
+
+// lays out the map of concerts, which must be sorted by date. nil when there
+// are no concerts at all.
+func newConcertMap(concerts []Concert) *concertMap {
+	if len(concerts) == 0 {
+		return nil
+	}
+	m := &concertMap{Width: mapWidth, Height: mapHeight}
+	var located []Concert
+	for _, c := range concerts {
+		if c.Located {
+			located = append(located, c)
+		} else {
+			m.Unlocated = append(m.Unlocated, c)
+		}
+	}
+	if len(located) == 0 {
+		return m
+	}
+
+	// the bounding box of the concerts, widened to the minimum span
+	minLat, maxLat := located[0].Lat, located[0].Lat
+	minLon, maxLon := located[0].Lon, located[0].Lon
+	for _, c := range located[1:] {
+		minLat, maxLat = math.Min(minLat, c.Lat), math.Max(maxLat, c.Lat)
+		minLon, maxLon = math.Min(minLon, c.Lon), math.Max(maxLon, c.Lon)
+	}
+	minLat, maxLat = widen(minLat, maxLat, mapMinSpan)
+	minLon, maxLon = widen(minLon, maxLon, mapMinSpan)
+
+	// degrees to pixels, the same scale both ways once longitudes are shrunk
+	k := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	scale := math.Min(
+		(mapWidth-2*mapPadding)/((maxLon-minLon)*k),
+		(mapHeight-2*mapPadding)/(maxLat-minLat),
+	)
+	centerLon, centerLat := (minLon+maxLon)/2, (minLat+maxLat)/2
+	project := func(lat, lon float64) (x, y float64) {
+		return mapWidth/2 + (lon-centerLon)*k*scale, mapHeight/2 - (lat-centerLat)*scale
+	}
+	unproject := func(x, y float64) (lat, lon float64) {
+		return centerLat - (y-mapHeight/2)/scale, centerLon + (x-mapWidth/2)/(k*scale)
+	}
+
+	bySlug := make(map[string]int)
+	var route []string
+	for _, c := range located {
+		i, ok := bySlug[c.Slug]
+		if !ok {
+			x, y := project(c.Lat, c.Lon)
+			i = len(m.Markers)
+			bySlug[c.Slug] = i
+			m.Markers = append(m.Markers, mapMarker{X: x, Y: y, Place: c.Place, Order: i + 1})
+		}
+		m.Markers[i].Concerts = append(m.Markers[i].Concerts, c)
+		point := fmt.Sprintf("%.1f,%.1f", m.Markers[i].X, m.Markers[i].Y)
+		if len(route) == 0 || route[len(route)-1] != point {
+			route = append(route, point)
+		}
+	}
+	if len(route) > 1 {
+		m.Route = strings.Join(route, " ")
+	}
+
+	// meridians and parallels across the whole visible area
+	top, left := unproject(0, 0)
+	bottom, right := unproject(mapWidth, mapHeight)
+	step := gridStep(math.Max(right-left, top-bottom))
+	for lon := math.Ceil(left/step) * step; lon <= right; lon += step {
+		x, _ := project(0, lon)
+		m.Grid = append(m.Grid, mapGridLine{X1: x, Y1: 0, X2: x, Y2: mapHeight, Label: degrees(lon, "E", "W")})
+	}
+	for lat := math.Ceil(bottom/step) * step; lat <= top; lat += step {
+		_, y := project(lat, 0)
+		m.Grid = append(m.Grid, mapGridLine{X1: 0, Y1: y, X2: mapWidth, Y2: y, Label: degrees(lat, "N", "S")})
+	}
+	return m
+}
+
+// grows [lo, hi] evenly around its middle to at least span
+func widen(lo, hi, span float64) (float64, float64) {
+	if hi-lo >= span {
+		return lo, hi
+	}
+	mid := (lo + hi) / 2
+	return mid - span/2, mid + span/2
+}
+
+// the smallest grid step that draws at most about eight lines across span degrees
+func gridStep(span float64) float64 {
+	for _, step := range mapGridSteps {
+		if span/step <= 8 {
+			return step
+		}
+	}
+	return mapGridSteps[len(mapGridSteps)-1]
+}
+
+// "30°N", "15°W", "0°"
+func degrees(v float64, pos, neg string) string {
+	v = math.Round(v*100) / 100
+	switch {
+	case v > 0:
+		return fmt.Sprintf("%g°%s", v, pos)
+	case v < 0:
+		return fmt.Sprintf("%g°%s", -v, neg)
+	}
+	return "0°"
+}
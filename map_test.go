@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewConcertMapEmpty(t *testing.T) {
+	if m := newConcertMap(nil); m != nil {
+		t.Errorf("map of no concerts = %+v, want nil", m)
+	}
+
+	nowhere := []Concert{{Place: ParsePlace("atlantis-sea")}, {Place: ParsePlace("mu-pacific")}}
+	m := newConcertMap(nowhere)
+	if m == nil {
+		t.Fatal("nil map for unlocated concerts")
+	}
+	if len(m.Unlocated) != 2 || m.Markers != nil || m.Route != "" || m.Grid != nil {
+		t.Errorf("map of unlocated concerts = %+v, want only Unlocated", m)
+	}
+}
+
+func TestNewConcertMap(t *testing.T) {
+	at := func(slug string, lat, lon float64) Concert {
+		p := ParsePlace(slug)
+		p.Coord, p.Located = Coord{Lat: lat, Lon: lon}, true
+		return Concert{Place: p}
+	}
+	concerts := []Concert{
+		at("paris-france", 48.8566, 2.3522),
+		at("paris-france", 48.8566, 2.3522),
+		{Place: ParsePlace("atlantis-sea")},
+		at("london-uk", 51.5074, -0.1278),
+		at("paris-france", 48.8566, 2.3522),
+	}
+	m := newConcertMap(concerts)
+	if len(m.Markers) != 2 || len(m.Unlocated) != 1 {
+		t.Fatalf("%d markers and %d unlocated, want 2 and 1", len(m.Markers), len(m.Unlocated))
+	}
+	paris, london := m.Markers[0], m.Markers[1]
+	if paris.Place.City != "Paris" || paris.Order != 1 || len(paris.Concerts) != 3 ||
+		london.Place.City != "London" || london.Order != 2 || len(london.Concerts) != 1 {
+		t.Errorf("markers = %+v", m.Markers)
+	}
+	// London is north-west of Paris
+	if !(london.X < paris.X && london.Y < paris.Y) {
+		t.Errorf("London at %.1f,%.1f and Paris at %.1f,%.1f", london.X, london.Y, paris.X, paris.Y)
+	}
+	for _, mk := range m.Markers {
+		if mk.X < mapPadding || mk.X > mapWidth-mapPadding || mk.Y < mapPadding || mk.Y > mapHeight-mapPadding {
+			t.Errorf("%s at %.1f,%.1f is outside the padded map", mk.Place, mk.X, mk.Y)
+		}
+	}
+	// repeated shows in one place are one point of the route
+	if points := strings.Fields(m.Route); len(points) != 3 || points[0] != points[2] {
+		t.Errorf("Route = %q, want Paris, London, Paris", m.Route)
+	}
+	if len(m.Grid) == 0 {
+		t.Error("no grid lines")
+	}
+}
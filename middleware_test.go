@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	loadPages(t)
+	h := newServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})).Handler
+
+	tests := []struct {
+		path        string
+		contentType string
+		want        string
+	}{
+		{"/artist/queen", "text/html; charset=utf-8", "<html"},
+		{"/api/v1/artists", "application/json; charset=utf-8", `"status":500`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != tt.contentType ||
+			!strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: got %d %s %q, want a 500 with %q", tt.path, w.Code, w.Header().Get("Content-Type"), w.Body.String(), tt.want)
+		}
+		if strings.Contains(w.Body.String(), "boom") {
+			t.Errorf("%s: the panic value reached the client", tt.path)
+		}
+	}
+}
+
+func TestRecoverPanicsAbortHandler(t *testing.T) {
+	h := recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	w := httptest.NewRecorder()
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler re-raised", v)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("wrote %q before aborting", w.Body.String())
+		}
+	}()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestMethodsAndBodyLimit(t *testing.T) {
+	loadPages(t)
+	h := newServer("", HandleRequests(assets)).Handler
+
+	form := func(size int) string {
+		return url.Values{"ArtistName": {strings.Repeat("a", size)}}.Encode()
+	}
+	tests := []struct {
+		method, path string
+		body         string
+		status       int
+		allow        string
+	}{
+		{http.MethodDelete, "/", "", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodPost, "/artist/queen", "", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodPut, "/artistInfo", "", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodPost, "/artistInfo", form(maxBodyBytes), http.StatusRequestEntityTooLarge, ""},
+		{http.MethodPost, "/artistInfo", form(0), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s: got %d with Allow %q, want %d with %q",
+				tt.method, tt.path, w.Code, w.Header().Get("Allow"), tt.status, tt.allow)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// concerts within a radius of a place:
+//
+//	GET /nearby?q=Paris&radius=500
+//	GET /nearby?lat=48.85&lon=2.35&radius=500
+//	GET /api/v1/nearby with the same parameters
+//
+// q is looked up in the gazetteer; lat and lon take precedence when given.
+
+const (
+	defaultRadiusKm = 500
+	maxRadiusKm     = 20040 // half the earth's circumference, i.e. everywhere
+)
+
+// the parameters of a nearby search, as typed, and the place they resolve to
+type nearbyQuery struct {
+	Name     string
+	Lat, Lon string
+	RadiusKm float64
+	Origin   Place // located; City holds the coordinates when searching by lat/lon
+}
+
+// reports whether the user asked for anything; an empty query shows the form only
+func (q nearbyQuery) Empty() bool {
+	return q.Name == "" && q.Lat == "" && q.Lon == ""
+}
+
+// reads ?q=, ?lat=, ?lon= and ?radius= (km). q is resolved with g.
+func parseNearby(g *Gazetteer, v url.Values) (nearbyQuery, error) {
+	q := nearbyQuery{
+		Name:     strings.TrimSpace(v.Get("q")),
+		Lat:      strings.TrimSpace(v.Get("lat")),
+		Lon:      strings.TrimSpace(v.Get("lon")),
+		RadiusKm: defaultRadiusKm,
+	}
+	if r := v.Get("radius"); r != "" {
+		radius, err := strconv.ParseFloat(r, 64)
+		// written so that NaN fails the range check too
+		if err != nil || !(radius > 0 && radius <= maxRadiusKm) {
+			return q, fmt.Errorf("radius %q must be a number of kilometres up to %d", r, maxRadiusKm)
+		}
+		q.RadiusKm = radius
+	}
+
+	switch {
+	case q.Lat != "" || q.Lon != "":
+		lat, errLat := strconv.ParseFloat(q.Lat, 64)
+		lon, errLon := strconv.ParseFloat(q.Lon, 64)
+		if errLat != nil || errLon != nil || !(lat >= -90 && lat <= 90) || !(lon >= -180 && lon <= 180) {
+			return q, fmt.Errorf("lat %q and lon %q must be decimal degrees, e.g. lat=48.85&lon=2.35", q.Lat, q.Lon)
+		}
+		q.Origin = Place{City: fmt.Sprintf("%.4f, %.4f", lat, lon), Coord: Coord{Lat: lat, Lon: lon}, Located: true}
+	case q.Name != "":
+		place, err := g.Find(q.Name)
+		if err != nil {
+			return q, err
+		}
+		q.Origin = place
+	}
+	return q, nil
+}
+
+// a concert and how far it is from the origin
+type nearbyConcert struct {
+	Concert
+	DistanceKm float64
+}
+
+// the concerts of one artist within the radius, closest first
+type nearbyArtist struct {
+	Artist    Data
+	NearestKm float64
+	Concerts  []nearbyConcert
+}
+
+// the concerts of every artist within radiusKm of origin, split into the
+// ones from today on and the ones before. artists are sorted by their
+// closest concert, their concerts by distance then date.
+func findNearby(snap *Snapshot, origin Coord, radiusKm float64, now time.Time) (upcoming, past []nearbyArtist) {
+	for _, d := range snap.Data {
+		var up, before []nearbyConcert
+		for _, c := range d.Concerts {
+			if !c.Located {
+				continue
+			}
+			dist := distanceKm(origin, c.Coord)
+			if dist > radiusKm {
+				continue
+			}
+			if c.Upcoming(now) {
+				up = append(up, nearbyConcert{Concert: c, DistanceKm: dist})
+			} else {
+				before = append(before, nearbyConcert{Concert: c, DistanceKm: dist})
+			}
+		}
+		if len(up) > 0 {
+			upcoming = append(upcoming, newNearbyArtist(d, up))
+		}
+		if len(before) > 0 {
+			past = append(past, newNearbyArtist(d, before))
+		}
+	}
+	sortNearby(upcoming)
+	sortNearby(past)
+	return upcoming, past
+}
+
+func newNearbyArtist(d Data, concerts []nearbyConcert) nearbyArtist {
+	sort.SliceStable(concerts, func(i, j int) bool {
+		if concerts[i].DistanceKm != concerts[j].DistanceKm {
+			return concerts[i].DistanceKm < concerts[j].DistanceKm
+		}
+		return concerts[i].Date.Before(concerts[j].Date)
+	})
+	return nearbyArtist{Artist: d, NearestKm: concerts[0].DistanceKm, Concerts: concerts}
+}
+
+func sortNearby(artists []nearbyArtist) {
+	sort.SliceStable(artists, func(i, j int) bool {
+		if artists[i].NearestKm != artists[j].NearestKm {
+			return artists[i].NearestKm < artists[j].NearestKm
+		}
+		return strings.ToLower(artists[i].Artist.A.Name) < strings.ToLower(artists[j].Artist.A.Name)
+	})
+}
+
+// a distance for display, e.g. "12 km"
+func formatKm(km float64) string {
+	return strconv.FormatFloat(math.Round(km), 'f', 0, 64) + " km"
+}
+
+// what nearby.html shows
+type nearbyView struct {
+	Query    nearbyQuery
+	Upcoming []nearbyArtist
+	Past     []nearbyArtist
+}
+
+// GET /nearby
+func nearbyPage(w http.ResponseWriter, r *http.Request) error {
+	q, err := parseNearby(gazetteer, r.URL.Query())
+	if err != nil {
+		return nearbyError(err)
+	}
+	view := nearbyView{Query: q}
+	if !q.Empty() {
+		snap, err := catalog.Get(r.Context())
+		if err != nil {
+			return unavailable(err)
+		}
+		view.Upcoming, view.Past = findNearby(snap, q.Origin.Coord, q.RadiusKm, clock.Now())
+	}
+	return render(w, "nearby.html", view)
+}
+
+// a bad nearby query is the user's mistake: 400 with the reason
+func nearbyError(err error) *AppError {
+	return newAppError(http.StatusBadRequest, "Invalid search: "+err.Error(), err)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseNearby(t *testing.T) {
+	g, err := loadGazetteer(strings.NewReader(gazetteerCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query  string
+		radius float64
+		origin string // Origin.City; "" when no place was asked for
+		lat    float64
+		err    bool
+	}{
+		{"", defaultRadiusKm, "", 0, false},
+		{"q=Paris", defaultRadiusKm, "Paris", 48.8566, false},
+		{"q=osaka-japan&radius=100", 100, "Osaka", 34.6937, false},
+		{"lat=48.85&lon=2.35&radius=20040", maxRadiusKm, "48.8500, 2.3500", 48.85, false},
+		{"lat=-90&lon=180", defaultRadiusKm, "-90.0000, 180.0000", -90, false},
+		{"q=Atlantis", 0, "", 0, true},
+		{"lat=48.85", 0, "", 0, true},
+		{"lat=91&lon=0", 0, "", 0, true},
+		{"lat=0&lon=-181", 0, "", 0, true},
+		{"lat=NaN&lon=2.35", 0, "", 0, true},
+		{"lat=48.85&lon=NaN", 0, "", 0, true},
+		{"lat=Inf&lon=0", 0, "", 0, true},
+		{"lat=0&lon=-Inf", 0, "", 0, true},
+		{"q=Paris&radius=0", 0, "", 0, true},
+		{"q=Paris&radius=-5", 0, "", 0, true},
+		{"q=Paris&radius=20041", 0, "", 0, true},
+		{"q=Paris&radius=NaN", 0, "", 0, true},
+		{"q=Paris&radius=Inf", 0, "", 0, true},
+		{"q=Paris&radius=far", 0, "", 0, true},
+	}
+	for _, tt := range tests {
+		v, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := parseNearby(g, v)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: no error, got %+v", tt.query, q)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		if q.RadiusKm != tt.radius || q.Origin.City != tt.origin || q.Origin.Lat != tt.lat {
+			t.Errorf("%q: radius %v, origin %q at %v; want %v, %q at %v",
+				tt.query, q.RadiusKm, q.Origin.City, q.Origin.Lat, tt.radius, tt.origin, tt.lat)
+		}
+		if tt.origin != "" && !q.Origin.Located {
+			t.Errorf("%q: origin not located", tt.query)
+		}
+	}
+}
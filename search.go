@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// what a suggestion matched, shown after its label
+const (
+	matchArtist       = "artist/band"
+	matchMember       = "member"
+	matchLocation     = "location"
+	matchFirstAlbum   = "first album"
+	matchCreationDate = "creation date"
+)
+
+// suggestions are listed in this order of match type
+var matchOrder = map[string]int{
+	matchArtist:       0,
+	matchMember:       1,
+	matchLocation:     2,
+	matchFirstAlbum:   3,
+	matchCreationDate: 4,
+}
+
+// the most suggestions /search/suggestions returns
+const maxSuggestions = 20
+
+// one search result, e.g. "Freddie Mercury – member"
+type Suggestion struct {
+	Label    string `json:"label"` // the matched value, e.g. "Freddie Mercury"
+	Type     string `json:"type"`  // one of the match* constants
+	ArtistID uint   `json:"artistId"`
+	Artist   string `json:"artist"` // name of the artist the value belongs to
+	URL      string `json:"url"`    // the artist's page
+}
+
+func (s Suggestion) String() string {
+	return s.Label + " – " + s.Type
+}
+
+// reports whether the artist's own name matched, rather than one of its values
+func (s Suggestion) IsArtist() bool {
+	return s.Type == matchArtist
+}
+
+// finds every artist name, member, concert location, first album date and
+// creation year containing query, ignoring case. each value is suggested
+// once per artist, ordered by type and then label.
+func Search(data []Data, query string) []Suggestion {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	var found []Suggestion
+	for _, d := range data {
+		seen := make(map[string]bool)
+		add := func(label, typ string) {
+			if !seen[typ+label] && strings.Contains(strings.ToLower(label), query) {
+				seen[typ+label] = true
+				found = append(found, Suggestion{Label: label, Type: typ, ArtistID: d.A.Id, Artist: d.A.Name, URL: d.URL()})
+			}
+		}
+		add(d.A.Name, matchArtist)
+		for _, member := range d.A.Members {
+			add(member, matchMember)
+		}
+		for _, c := range d.Concerts {
+			add(c.Place.String(), matchLocation)
+		}
+		add(d.A.FirstAlbum, matchFirstAlbum)
+		add(strconv.Itoa(int(d.A.CreationDate)), matchCreationDate)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Type != found[j].Type {
+			return matchOrder[found[i].Type] < matchOrder[found[j].Type]
+		}
+		return found[i].Label < found[j].Label
+	})
+	return found
+}
+
+// the artists any suggestion points at, in catalog order
+func searchArtists(data []Data, suggestions []Suggestion) []Data {
+	ids := make(map[uint]bool, len(suggestions))
+	for _, s := range suggestions {
+		ids[s.ArtistID] = true
+	}
+	var artists []Data
+	for _, d := range data {
+		if ids[d.A.Id] {
+			artists = append(artists, d)
+		}
+	}
+	return artists
+}
+
+// shows the home page with only the artists matching ?q=, plus what matched
+func searchPage(w http.ResponseWriter, r *http.Request) error {
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	query := r.FormValue("q")
+	if strings.TrimSpace(query) == "" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return nil
+	}
+	suggestions := Search(snap.Data, query)
+	return render(w, "index.html", homeView{
+		Artists:     searchArtists(snap.Data, suggestions),
+		Query:       query,
+		Suggestions: suggestions,
+		Options:     newFilterOptions(snap.Data),
+	})
+}
+
+// answers ?q= with up to maxSuggestions suggestions as JSON, for the search box
+func searchSuggestions(w http.ResponseWriter, r *http.Request) error {
+	snap, err := catalog.Get(r.Context())
+	if err != nil {
+		return unavailable(err)
+	}
+	suggestions := Search(snap.Data, r.FormValue("q"))
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	type item struct {
+		Suggestion
+		Text string `json:"text"` // e.g. "Freddie Mercury – member"
+	}
+	items := make([]item, len(suggestions))
+	for i, s := range suggestions {
+		items[i] = item{s, s.String()}
+	}
+	return writeJSON(w, http.StatusOK, items)
+}
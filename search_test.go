@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// a small catalog for the search, filter and lookup tests
+func testCatalog() []Data {
+	artist := func(id uint, name, slug string, created uint, album string, members []string, places ...string) Data {
+		d := Data{A: Artist{Id: id, Name: name, CreationDate: created, FirstAlbum: album, Members: members}, Slug: slug}
+		for _, p := range places {
+			d.Concerts = append(d.Concerts, Concert{ArtistID: id, Place: ParsePlace(p)})
+		}
+		return d
+	}
+	return []Data{
+		artist(1, "Queen", "queen", 1970, "14-12-1973",
+			[]string{"Freddie Mercury", "Brian May", "Roger Taylor", "John Deacon"},
+			"osaka-japan", "los_angeles-usa", "osaka-japan"),
+		artist(2, "Pink Floyd", "pink-floyd", 1965, "05-08-1967",
+			[]string{"Syd Barrett", "Roger Waters", "Nick Mason", "Richard Wright", "David Gilmour"},
+			"london-uk"),
+		artist(3, "Mercury Rev", "mercury-rev", 1989, "not a date",
+			[]string{"Jonathan Donahue"},
+			"saitama-japan"),
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string // Suggestion.String() with the artist's id
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"beatles", nil},
+		// an artist name before a member, whichever artist comes first
+		{"mercury", []string{"3 Mercury Rev – artist/band", "1 Freddie Mercury – member"}},
+		// case is ignored and Queen's two Osaka concerts give one suggestion
+		{"OSAKA", []string{"1 Osaka, Japan – location"}},
+		{"  roger ", []string{"1 Roger Taylor – member", "2 Roger Waters – member"}},
+		{"japan", []string{"1 Osaka, Japan – location", "3 Saitama, Japan – location"}},
+		{"19", []string{
+			"2 05-08-1967 – first album",
+			"1 14-12-1973 – first album",
+			"2 1965 – creation date",
+			"1 1970 – creation date",
+			"3 1989 – creation date",
+		}},
+	}
+	data := testCatalog()
+	for _, tt := range tests {
+		var got []string
+		for _, s := range Search(data, tt.query) {
+			got = append(got, fmt.Sprint(s.ArtistID, " ", s))
+			if byID(data, s.ArtistID).A.Name != s.Artist || s.URL != byID(data, s.ArtistID).URL() {
+				t.Errorf("%q: %v points at %q, %q", tt.query, s, s.Artist, s.URL)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchArtists(t *testing.T) {
+	data := testCatalog()
+	var ids []uint
+	for _, d := range searchArtists(data, Search(data, "japan")) {
+		ids = append(ids, d.A.Id)
+	}
+	if want := []uint{1, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("artists = %v, want %v", ids, want)
+	}
+}
+
+func byID(data []Data, id uint) Data {
+	for _, d := range data {
+		if d.A.Id == id {
+			return d
+		}
+	}
+	return Data{}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// limits for every request the server accepts
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 10 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	maxHeaderBytes    = 64 << 10 // 64 KiB
+	maxBodyBytes      = 64 << 10 // forms here only ever carry an artist name
+)
+
+// the server with its timeouts and size limits, serving mux behind the
+// panic recovery and body limit middleware
+func newServer(addr string, mux http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           chain(mux, recoverPanics, limitBody(maxBodyBytes)),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+}
+
+// opens the configured unix socket, or the TCP address otherwise.
+// a socket file left behind by a crashed run is removed first.
+func listen(cfg config) (net.Listener, error) {
+	if cfg.Socket == "" {
+		return net.Listen("tcp", cfg.Addr())
+	}
+	if fi, err := os.Stat(cfg.Socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", cfg.Socket); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("listen unix %s: another server is using the socket", cfg.Socket)
+		}
+		os.Remove(cfg.Socket)
+	}
+	return net.Listen("unix", cfg.Socket)
+}
+
+// serves handler on ln, opened with listen, until ctx is done, then stops
+// accepting connections and gives in-flight requests up to
+// cfg.ShutdownTimeout to finish.
+func serve(ctx context.Context, cfg config, ln net.Listener, handler http.Handler) error {
+	srv := newServer(ln.Addr().String(), handler)
+	fmt.Printf("Serving at %s://%s\n", ln.Addr().Network(), ln.Addr())
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Shutting down, waiting for open requests (interrupt again to quit now)...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// fills the datalist of the search box with typed suggestions while the user types
+(() => {
+    const box = document.querySelector('.search input[name="q"]');
+    const list = document.getElementById('suggestions');
+    if (!box || !list) {
+        return;
+    }
+    box.addEventListener('input', async () => {
+        const resp = await fetch('/search/suggestions?q=' + encodeURIComponent(box.value));
+        if (!resp.ok) {
+            return;
+        }
+        const suggestions = await resp.json();
+        list.replaceChildren(...suggestions.map(s => {
+            const option = document.createElement('option');
+            option.value = s.label;
+            option.label = s.text;
+            return option;
+        }));
+    });
+})();
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// the page templates and static files, built into the binary so the site
+// doesn't depend on the working directory
+var (
+	//go:embed templates
+	embeddedTemplates embed.FS
+
+	//go:embed static
+	embeddedStatic embed.FS
+)
+
+// where -dev reads templates and static files from instead
+const (
+	templatesDir = "templates"
+	staticDir    = "static"
+)
+
+// every page is parsed together with the layout, which it fills by defining
+// "title", "head", "content" and "scripts", and with the shared partials
+const layoutFile = "layout.html"
+
+var sharedTemplates = []string{layoutFile, "partials/*.html"}
+
+// the parsed page templates, keyed by file name, e.g. "index.html".
+// each is executed through its "layout" template, see render.
+// in dev mode they are re-read from disk whenever a file changes.
+type templateSet struct {
+	fsys fs.FS
+	dev  bool
+
+	mu       sync.RWMutex
+	pages    map[string]*template.Template
+	modTimes map[string]time.Time // dev mode: file name -> modification time at last parse
+}
+
+// the templates every handler renders with, set in run
+var templates *templateSet
+
+// parses every template up front so a broken one stops the server at startup.
+// dev mode reads them from templatesDir and reparses on change.
+func newTemplateSet(dev bool) (*templateSet, error) {
+	s := &templateSet{dev: dev}
+	if dev {
+		s.fsys = os.DirFS(templatesDir)
+	} else {
+		sub, err := fs.Sub(embeddedTemplates, templatesDir)
+		if err != nil {
+			return nil, err
+		}
+		s.fsys = sub
+	}
+	if err := s.parse(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parses every page (the *.html files next to the layout) into its own template
+func (s *templateSet) parse() error {
+	files, err := fs.Glob(s.fsys, "*.html")
+	if err != nil {
+		return err
+	}
+	pages := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		if file == layoutFile {
+			continue
+		}
+		patterns := append(append([]string{}, sharedTemplates...), file)
+		t, err := template.New(file).Funcs(templateFuncs).ParseFS(s.fsys, patterns...)
+		if err != nil {
+			return fmt.Errorf("parsing templates: %w", err)
+		}
+		pages[file] = t
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("no templates found")
+	}
+	modTimes, err := s.stat()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.pages, s.modTimes = pages, modTimes
+	s.mu.Unlock()
+	return nil
+}
+
+// the modification time of every template file, only tracked in dev mode
+func (s *templateSet) stat() (map[string]time.Time, error) {
+	if !s.dev {
+		return nil, nil
+	}
+	files, err := fs.Glob(s.fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	partials, err := fs.Glob(s.fsys, "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, partials...)
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		fi, err := fs.Stat(s.fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = fi.ModTime()
+	}
+	return modTimes, nil
+}
+
+// reports whether a template file was added, removed or modified since the last parse
+func (s *templateSet) changed() bool {
+	modTimes, err := s.stat()
+	if err != nil {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(modTimes) != len(s.modTimes) {
+		return true
+	}
+	for file, t := range modTimes {
+		if !t.Equal(s.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the template of the named page. in dev mode a changed file is
+// reparsed first; a parse error is returned so it shows up in the browser log.
+func (s *templateSet) Lookup(name string) (*template.Template, error) {
+	if s.dev && s.changed() {
+		if err := s.parse(); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("no template %q", name)
+	}
+	return t, nil
+}
+
+// the static files, from the binary or from staticDir in dev mode
+func staticFiles(dev bool) (fs.FS, error) {
+	if dev {
+		return os.DirFS(staticDir), nil
+	}
+	return fs.Sub(embeddedStatic, staticDir)
+}
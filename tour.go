@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+)
+
+// how concerts are split into tours. the dates endpoint marks tour openers
+// with "*", but some artists have every show marked, so a marker only starts
+// a new tour after a break of tourOpenerGap. a break longer than tourBreak
+// starts one even without a marker.
+const (
+	tourOpenerGap = 14 * 24 * time.Hour
+	tourBreak     = 60 * 24 * time.Hour
+)
+
+// a run of an artist's concerts
+type Tour struct {
+	Number      int       // 1 for the artist's first tour
+	Concerts    []Concert // by date
+	First, Last time.Time
+	Countries   []string // in the order first visited
+	DistanceKm  float64  // from concert to concert, great-circle
+	Unlocated   int      // concerts that couldn't be placed, left out of DistanceKm
+}
+
+// the number of shows
+func (t Tour) Shows() int {
+	return len(t.Concerts)
+}
+
+// "Aug 2019", or "Jan – Feb 2020" for a tour over several months
+func (t Tour) Dates() string {
+	switch {
+	case t.First.Year() != t.Last.Year():
+		return t.First.Format("Jan 2006") + " – " + t.Last.Format("Jan 2006")
+	case t.First.Month() != t.Last.Month():
+		return t.First.Format("Jan") + " – " + t.Last.Format("Jan 2006")
+	}
+	return t.First.Format("Jan 2006")
+}
+
+// splits concerts, sorted by date, into tours and works out their statistics
+func buildTours(concerts []Concert) []Tour {
+	var tours []Tour
+	start := 0
+	for i := 1; i <= len(concerts); i++ {
+		if i < len(concerts) {
+			gap := concerts[i].Date.Sub(concerts[i-1].Date)
+			if gap <= tourBreak && !(concerts[i].TourOpener && gap >= tourOpenerGap) {
+				continue
+			}
+		}
+		tours = append(tours, newTour(len(tours)+1, concerts[start:i]))
+		start = i
+	}
+	return tours
+}
+
+func newTour(number int, concerts []Concert) Tour {
+	t := Tour{
+		Number:   number,
+		Concerts: concerts,
+		First:    concerts[0].Date,
+		Last:     concerts[len(concerts)-1].Date,
+	}
+	seen := make(map[string]bool)
+	var prev *Concert
+	for i := range concerts {
+		c := &concerts[i]
+		if c.Country != "" && !seen[c.Country] {
+			seen[c.Country] = true
+			t.Countries = append(t.Countries, c.Country)
+		}
+		if !c.Located {
+			t.Unlocated++
+			continue
+		}
+		if prev != nil {
+			t.DistanceKm += distanceKm(prev.Coord, c.Coord)
+		}
+		prev = c
+	}
+	return t
+}
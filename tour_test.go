@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// a concert on "02-01-2006", marked as a tour opener when the date starts with "*"
+func testShow(t *testing.T, date string, place Place) Concert {
+	t.Helper()
+	opener := date[0] == '*'
+	if opener {
+		date = date[1:]
+	}
+	d, err := time.Parse(apiDateLayout, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Concert{Place: place, Date: d, TourOpener: opener}
+}
+
+func TestBuildToursSplits(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates []string
+		shows []int // shows per tour
+	}{
+		{"none", nil, nil},
+		{"one show", []string{"01-03-2019"}, []int{1}},
+		{"short gaps", []string{"01-03-2019", "20-03-2019", "10-05-2019"}, []int{3}},
+		{"gap of exactly tourBreak", []string{"01-03-2019", "30-04-2019"}, []int{2}},
+		{"gap over tourBreak", []string{"01-03-2019", "01-05-2019"}, []int{1, 1}},
+		{"opener after tourOpenerGap", []string{"01-03-2019", "*15-03-2019"}, []int{1, 1}},
+		{"opener within tourOpenerGap", []string{"01-03-2019", "*14-03-2019"}, []int{2}},
+		{"every show an opener", []string{"*01-03-2019", "*03-03-2019", "*05-03-2019", "*01-04-2019", "*02-04-2019"}, []int{3, 2}},
+		{"opener and long break", []string{"*01-01-2019", "02-01-2019", "*01-06-2019", "01-12-2019"}, []int{2, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var concerts []Concert
+			for _, d := range tt.dates {
+				concerts = append(concerts, testShow(t, d, Place{}))
+			}
+			var shows []int
+			for i, tour := range buildTours(concerts) {
+				if tour.Number != i+1 {
+					t.Errorf("tour %d numbered %d", i+1, tour.Number)
+				}
+				shows = append(shows, tour.Shows())
+			}
+			if !reflect.DeepEqual(shows, tt.shows) {
+				t.Errorf("shows per tour = %v, want %v", shows, tt.shows)
+			}
+		})
+	}
+}
+
+func TestBuildToursStatistics(t *testing.T) {
+	located := func(slug string, lat, lon float64) Place {
+		p := ParsePlace(slug)
+		p.Coord, p.Located = Coord{Lat: lat, Lon: lon}, true
+		return p
+	}
+	paris := located("paris-france", 48.8566, 2.3522)
+	london := located("london-uk", 51.5074, -0.1278)
+	lyon := located("lyon-france", 45.7640, 4.8357)
+	nowhere := ParsePlace("nowhere-uk")
+
+	tours := buildTours([]Concert{
+		testShow(t, "*01-03-2019", paris),
+		testShow(t, "03-03-2019", london),
+		testShow(t, "05-03-2019", nowhere),
+		testShow(t, "07-03-2019", lyon),
+		testShow(t, "02-04-2019", paris),
+	})
+	if len(tours) != 1 {
+		t.Fatalf("%d tours, want 1", len(tours))
+	}
+	tour := tours[0]
+	if want := []string{"France", "UK"}; !reflect.DeepEqual(tour.Countries, want) {
+		t.Errorf("Countries = %v, want %v", tour.Countries, want)
+	}
+	if tour.Unlocated != 1 {
+		t.Errorf("Unlocated = %d, want 1", tour.Unlocated)
+	}
+	// the unlocated show is skipped, not treated as a stop at 0,0
+	want := distanceKm(paris.Coord, london.Coord) + distanceKm(london.Coord, lyon.Coord) + distanceKm(lyon.Coord, paris.Coord)
+	if tour.DistanceKm != want {
+		t.Errorf("DistanceKm = %v, want %v", tour.DistanceKm, want)
+	}
+	if !tour.First.Equal(tours[0].Concerts[0].Date) || tour.Last.Format(apiDateLayout) != "02-04-2019" {
+		t.Errorf("First, Last = %v, %v", tour.First, tour.Last)
+	}
+}
+
+func TestTourDates(t *testing.T) {
+	tests := []struct {
+		first, last string
+		want        string
+	}{
+		{"01-08-2019", "01-08-2019", "Aug 2019"},
+		{"01-08-2019", "31-08-2019", "Aug 2019"},
+		{"20-01-2020", "10-02-2020", "Jan – Feb 2020"},
+		{"10-12-2019", "05-01-2020", "Dec 2019 – Jan 2020"},
+	}
+	for _, tt := range tests {
+		tour := Tour{First: testShow(t, tt.first, Place{}).Date, Last: testShow(t, tt.last, Place{}).Date}
+		if got := tour.Dates(); got != tt.want {
+			t.Errorf("%s to %s: Dates() = %q, want %q", tt.first, tt.last, got, tt.want)
+		}
+	}
+}